@@ -0,0 +1,548 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/scenario"
+	"github.com/francescomari/metrics-generator/pkg/client"
+)
+
+// cliCommand is a subcommand that controls a running generator through its
+// API.
+type cliCommand struct {
+	usage       string
+	description string
+	run         func(ctx context.Context, c *cliContext, args []string) error
+
+	// untimed commands are not limited by the timeout, which applies to
+	// every call to the API instead.
+	untimed bool
+}
+
+var cliCommands map[string]cliCommand
+
+func init() {
+	cliCommands = map[string]cliCommand{
+		"get": {
+			usage:       "get [duration-interval | errors-percentage | requests-hour]",
+			description: "Print the configuration, or a single setting",
+			run:         runGetCommand,
+		},
+		"set": {
+			usage:       "set <setting> <value>",
+			description: "Change a setting: duration-interval as min,max, errors-percentage or requests-hour",
+			run:         runSetCommand,
+		},
+		"status": {
+			usage:       "status",
+			description: "Print the health, the configuration and recent statistics",
+			run:         runStatusCommand,
+		},
+		"reload": {
+			usage:       "reload",
+			description: "Reload the configuration file",
+			run:         runReloadCommand,
+		},
+		"restart": {
+			usage:       "restart",
+			description: "Simulate a restart, resetting the synthetic metrics",
+			run:         runRestartCommand,
+		},
+		"scenario": {
+			usage:       "scenario start <file>",
+			description: "Run the timeline of changes described by a scenario file",
+			run:         runScenarioCommand,
+			untimed:     true,
+		},
+		"completion": {
+			usage:       "completion bash",
+			description: "Print a shell completion script",
+			run:         runCompletionCommand,
+		},
+	}
+}
+
+// settingNames are the settings accepted by the get and set commands.
+var settingNames = []string{"duration-interval", "errors-percentage", "requests-hour"}
+
+// isCLICommand returns whether the command line arguments invoke a
+// subcommand instead of running the generator.
+func isCLICommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	_, ok := cliCommands[args[0]]
+
+	return ok
+}
+
+type cliContext struct {
+	client  *client.Client
+	output  string
+	timeout time.Duration
+	stdout  io.Writer
+}
+
+// runCLICommand runs the subcommand named by the first argument. The flags
+// following the name of the subcommand configure how to reach the generator,
+// and can be set through environment variables like the flags of the
+// generator.
+func runCLICommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	name := args[0]
+	command := cliCommands[name]
+
+	flags := flag.NewFlagSet("metrics-generator "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var (
+		c                  client.Client
+		output             string
+		timeout            time.Duration
+		tokenFile          string
+		caFile             string
+		certFile           string
+		keyFile            string
+		insecureSkipVerify bool
+	)
+
+	flags.StringVar(&c.BaseURL, "url", "http://localhost:8080", "URL of the generator")
+	flags.StringVar(&c.Token, "token", "", "Bearer token to authenticate with")
+	flags.StringVar(&tokenFile, "token-file", "", "Path to a file containing the bearer token")
+	flags.StringVar(&c.Username, "username", "", "Username for basic authentication")
+	flags.StringVar(&c.Password, "password", "", "Password for basic authentication")
+	flags.StringVar(&caFile, "ca-file", "", "Path to the CA certificates used to verify the generator")
+	flags.StringVar(&certFile, "cert-file", "", "Path to the client certificate, for generators requiring mTLS")
+	flags.StringVar(&keyFile, "key-file", "", "Path to the client private key, for generators requiring mTLS")
+	flags.BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "Don't verify the certificate of the generator")
+	flags.StringVar(&output, "output", "text", "Output format: text or json")
+	flags.DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for the whole command, or for every call to the API of a scenario")
+
+	flags.VisitAll(func(f *flag.Flag) {
+		f.Usage = fmt.Sprintf("%s [$%s]", f.Usage, envName(f.Name))
+	})
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: metrics-generator %s\n\n%s.\n\nFlags:\n", command.usage, command.description)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args[1:]); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := applyEnv(flags); err != nil {
+		return fmt.Errorf("environment: %v", err)
+	}
+
+	if output != "text" && output != "json" {
+		return fmt.Errorf("invalid output format %q", output)
+	}
+
+	if tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return fmt.Errorf("read token file: %v", err)
+		}
+
+		c.Token = strings.TrimSpace(string(data))
+	}
+
+	tlsConfig, err := cliTLSConfig(caFile, certFile, keyFile, insecureSkipVerify)
+	if err != nil {
+		return err
+	}
+
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		c.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	if !command.untimed {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cli := cliContext{
+		client:  &c,
+		output:  output,
+		timeout: timeout,
+		stdout:  stdout,
+	}
+
+	return command.run(ctx, &cli, flags.Args())
+}
+
+func cliTLSConfig(caFile, certFile, keyFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	if caFile == "" && certFile == "" && keyFile == "" && !insecureSkipVerify {
+		return nil, nil
+	}
+
+	config := tls.Config{
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in CA file")
+		}
+
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %v", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return &config, nil
+}
+
+func (c *cliContext) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func runGetCommand(ctx context.Context, c *cliContext, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments")
+	}
+
+	config, err := c.client.Config(ctx)
+	if err != nil {
+		return err
+	}
+
+	values := settingValues(config)
+
+	if len(args) == 0 {
+		if c.output == "json" {
+			return c.printJSON(config)
+		}
+
+		for _, name := range settingNames {
+			fmt.Fprintf(c.stdout, "%s: %s\n", name, values[name])
+		}
+
+		return nil
+	}
+
+	value, ok := values[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown setting %q", args[0])
+	}
+
+	if c.output == "json" {
+		return c.printJSON(map[string]string{args[0]: value})
+	}
+
+	fmt.Fprintln(c.stdout, value)
+
+	return nil
+}
+
+func settingValues(config *client.Config) map[string]string {
+	return map[string]string{
+		"duration-interval": fmt.Sprintf("%d,%d", config.DurationInterval.Min, config.DurationInterval.Max),
+		"errors-percentage": strconv.FormatFloat(config.ErrorsPercentage, 'f', -1, 64),
+		"requests-hour":     strconv.Itoa(config.RequestsHour),
+	}
+}
+
+func runSetCommand(ctx context.Context, c *cliContext, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected a setting and a value")
+	}
+
+	name, value := args[0], args[1]
+
+	apply, err := scenario.ParseSetting(name, value)
+	if err != nil {
+		return err
+	}
+
+	if err := apply(ctx, c.client); err != nil {
+		return err
+	}
+
+	if c.output == "json" {
+		return c.printJSON(map[string]string{name: value})
+	}
+
+	fmt.Fprintf(c.stdout, "%s set to %s\n", name, value)
+
+	return nil
+}
+
+func runStatusCommand(ctx context.Context, c *cliContext, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+
+	type Status struct {
+		Healthy bool           `json:"healthy"`
+		Error   string         `json:"error,omitempty"`
+		Config  *client.Config `json:"config,omitempty"`
+		Stats   *client.Stats  `json:"stats,omitempty"`
+	}
+
+	var status Status
+
+	if err := c.client.Health(ctx); err != nil {
+		status.Error = err.Error()
+	} else {
+		status.Healthy = true
+	}
+
+	if status.Healthy {
+		config, err := c.client.Config(ctx)
+		if err != nil {
+			return err
+		}
+
+		status.Config = config
+
+		// Statistics might not be served, e.g. by older versions.
+		stats, err := c.client.Stats(ctx, nil, nil)
+		if err == nil {
+			status.Stats = stats
+		}
+	}
+
+	if c.output == "json" {
+		if err := c.printJSON(status); err != nil {
+			return err
+		}
+	} else {
+		printStatus(c.stdout, status.Healthy, status.Error, status.Config, status.Stats)
+	}
+
+	if !status.Healthy {
+		return fmt.Errorf("generator is not healthy")
+	}
+
+	return nil
+}
+
+func printStatus(w io.Writer, healthy bool, healthError string, config *client.Config, stats *client.Stats) {
+	if !healthy {
+		fmt.Fprintf(w, "health: unhealthy (%s)\n", healthError)
+		return
+	}
+
+	fmt.Fprintln(w, "health: healthy")
+
+	values := settingValues(config)
+
+	for _, name := range settingNames {
+		fmt.Fprintf(w, "%s: %s\n", name, values[name])
+	}
+
+	if stats == nil || len(stats.Windows) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	var quantiles []string
+
+	for q := range stats.Windows[0].Quantiles {
+		quantiles = append(quantiles, q)
+	}
+
+	sort.Slice(quantiles, func(i, j int) bool {
+		a, _ := strconv.ParseFloat(quantiles[i], 64)
+		b, _ := strconv.ParseFloat(quantiles[j], 64)
+		return a < b
+	})
+
+	fmt.Fprint(tw, "WINDOW\tREQUESTS\tRATE/S\tERRORS\tERROR RATIO")
+
+	for _, q := range quantiles {
+		v, _ := strconv.ParseFloat(q, 64)
+		fmt.Fprintf(tw, "\tP%s", strconv.FormatFloat(v*100, 'f', -1, 64))
+	}
+
+	fmt.Fprintln(tw)
+
+	for _, window := range stats.Windows {
+		fmt.Fprintf(tw, "%s\t%d\t%.3f\t%d\t%.3f", window.Window, window.Requests, window.Rate, window.Errors, window.ErrorRatio)
+
+		for _, q := range quantiles {
+			fmt.Fprintf(tw, "\t%g", window.Quantiles[q])
+		}
+
+		fmt.Fprintln(tw)
+	}
+
+	tw.Flush()
+}
+
+func runReloadCommand(ctx context.Context, c *cliContext, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+
+	if err := c.client.Reload(ctx); err != nil {
+		return err
+	}
+
+	if c.output == "json" {
+		return c.printJSON(map[string]bool{"reloaded": true})
+	}
+
+	fmt.Fprintln(c.stdout, "configuration reloaded")
+
+	return nil
+}
+
+func runRestartCommand(ctx context.Context, c *cliContext, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+
+	if err := c.client.Restart(ctx); err != nil {
+		return err
+	}
+
+	if c.output == "json" {
+		return c.printJSON(map[string]bool{"restarted": true})
+	}
+
+	fmt.Fprintln(c.stdout, "restart simulated")
+
+	return nil
+}
+
+func runScenarioCommand(ctx context.Context, c *cliContext, args []string) error {
+	if len(args) != 2 || args[0] != "start" {
+		return fmt.Errorf("expected start and the path of a scenario file")
+	}
+
+	s, err := scenario.Load(args[1])
+	if err != nil {
+		return fmt.Errorf("load scenario: %v", err)
+	}
+
+	runner := scenario.Runner{
+		Client:  c.client,
+		Timeout: c.timeout,
+		OnAction: func(at time.Duration, description string) {
+			if c.output == "json" {
+				c.printJSON(map[string]string{"at": at.String(), "action": description})
+			} else {
+				fmt.Fprintf(c.stdout, "%s: %s\n", at, description)
+			}
+		},
+	}
+
+	return runner.Run(ctx, s)
+}
+
+func runCompletionCommand(ctx context.Context, c *cliContext, args []string) error {
+	if len(args) != 1 || args[0] != "bash" {
+		return fmt.Errorf("only bash completion is supported")
+	}
+
+	var commands []string
+
+	for name := range cliCommands {
+		commands = append(commands, name)
+	}
+
+	sort.Strings(commands)
+
+	fmt.Fprintf(c.stdout, bashCompletion, strings.Join(commands, " "), strings.Join(settingNames, " "))
+
+	return nil
+}
+
+const bashCompletion = `_metrics_generator() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local commands="serve %s"
+    local settings="%s"
+
+    if [[ ${COMP_CWORD} -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "${commands}" -- "${cur}"))
+        return
+    fi
+
+    if [[ "${cur}" == -* ]]; then
+        local flags
+        flags=$("${COMP_WORDS[0]}" "${COMP_WORDS[1]}" -help 2>&1 | grep -o '^  -[a-z-]*' | tr -d ' ')
+        COMPREPLY=($(compgen -W "${flags}" -- "${cur}"))
+        return
+    fi
+
+    case "${COMP_WORDS[1]}" in
+        get|set)
+            COMPREPLY=($(compgen -W "${settings}" -- "${cur}"))
+            ;;
+        scenario)
+            if [[ "${COMP_WORDS[COMP_CWORD-1]}" == "start" ]]; then
+                COMPREPLY=($(compgen -f -- "${cur}"))
+            else
+                COMPREPLY=($(compgen -W "start" -- "${cur}"))
+            fi
+            ;;
+        completion)
+            COMPREPLY=($(compgen -W "bash" -- "${cur}"))
+            ;;
+    esac
+}
+
+complete -F _metrics_generator metrics-generator
+`
+
+// printCLIUsage prints the list of subcommands, appended to the usage of the
+// generator.
+func printCLIUsage(w io.Writer) {
+	var names []string
+
+	for name := range cliCommands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "\nCommands to control a running generator, see metrics-generator <command> -help:\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, cliCommands[name].description)
+	}
+
+	tw.Flush()
+}
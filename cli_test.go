@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/auth"
+	"github.com/francescomari/metrics-generator/internal/limits"
+)
+
+func newCLIServer(t *testing.T, handler *api.Handler) string {
+	t.Helper()
+
+	var config limits.Config
+
+	if err := config.SetDurationInterval(1, 10); err != nil {
+		t.Fatalf("set duration interval: %v", err)
+	}
+
+	if err := config.SetErrorsPercentage(10); err != nil {
+		t.Fatalf("set errors percentage: %v", err)
+	}
+
+	if err := config.SetRequestsHour(1000); err != nil {
+		t.Fatalf("set requests hour: %v", err)
+	}
+
+	handler.Config = &config
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func runCLI(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+
+	err := runCLICommand(context.Background(), args, &stdout, &stderr)
+
+	return stdout.String(), err
+}
+
+func TestCLISetAndGet(t *testing.T) {
+	url := newCLIServer(t, &api.Handler{})
+
+	if _, err := runCLI(t, "set", "-url", url, "duration-interval", "15,45"); err != nil {
+		t.Fatalf("set duration interval: %v", err)
+	}
+
+	if _, err := runCLI(t, "set", "-url", url, "errors-percentage", "25"); err != nil {
+		t.Fatalf("set errors percentage: %v", err)
+	}
+
+	output, err := runCLI(t, "get", "-url", url)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	want := "duration-interval: 15,45\nerrors-percentage: 25\nrequests-hour: 1000\n"
+
+	if output != want {
+		t.Fatalf("invalid output: got %q, want %q", output, want)
+	}
+
+	output, err = runCLI(t, "get", "-url", url, "-output", "json", "duration-interval")
+	if err != nil {
+		t.Fatalf("get duration interval: %v", err)
+	}
+
+	if !strings.Contains(output, `"duration-interval": "15,45"`) {
+		t.Fatalf("invalid output: %q", output)
+	}
+}
+
+func TestCLIInvalidArguments(t *testing.T) {
+	url := newCLIServer(t, &api.Handler{})
+
+	for _, args := range [][]string{
+		{"get", "-url", url, "boom"},
+		{"get", "-url", url, "-output", "yaml"},
+		{"set", "-url", url, "errors-percentage"},
+		{"set", "-url", url, "errors-percentage", "200"},
+		{"set", "-url", url, "duration-interval", "15"},
+		{"set", "-url", url, "boom", "1"},
+	} {
+		if _, err := runCLI(t, args...); err == nil {
+			t.Errorf("no error returned for %v", args)
+		}
+	}
+}
+
+func TestCLIAuthentication(t *testing.T) {
+	credentials, err := auth.Parse([]byte("tokens:\n  - token: secret\n    role: admin\n"))
+	if err != nil {
+		t.Fatalf("parse credentials: %v", err)
+	}
+
+	url := newCLIServer(t, &api.Handler{Authenticator: credentials})
+
+	if _, err := runCLI(t, "set", "-url", url, "requests-hour", "10"); err == nil {
+		t.Fatalf("no error returned")
+	}
+
+	t.Setenv("METRICS_GENERATOR_TOKEN", "secret")
+
+	if _, err := runCLI(t, "set", "-url", url, "requests-hour", "10"); err != nil {
+		t.Fatalf("set requests hour: %v", err)
+	}
+}
+
+func TestCLIStatus(t *testing.T) {
+	url := newCLIServer(t, &api.Handler{})
+
+	output, err := runCLI(t, "status", "-url", url)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	if !strings.HasPrefix(output, "health: healthy\n") {
+		t.Fatalf("invalid output: %q", output)
+	}
+
+	if _, err := runCLI(t, "status", "-url", "http://127.0.0.1:1"); err == nil {
+		t.Fatalf("no error returned for unreachable generator")
+	}
+}
+
+func TestIsCLICommand(t *testing.T) {
+	if !isCLICommand([]string{"get"}) {
+		t.Errorf("get is not a command")
+	}
+
+	for _, args := range [][]string{nil, {"serve"}, {"-addr", ":8080"}} {
+		if isCLICommand(args) {
+			t.Errorf("%v is a command", args)
+		}
+	}
+}
+
+func TestCLIScenario(t *testing.T) {
+	url := newCLIServer(t, &api.Handler{})
+
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+
+	data := "steps:\n  - set:\n      errors-percentage: 25\n  - at: 10ms\n    set:\n      requests-hour: 3600\n"
+
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("write scenario: %v", err)
+	}
+
+	output, err := runCLI(t, "scenario", "-url", url, "start", path)
+	if err != nil {
+		t.Fatalf("scenario: %v", err)
+	}
+
+	want := "0s: set errors-percentage 25\n10ms: set requests-hour 3600\n"
+
+	if output != want {
+		t.Fatalf("invalid output: got %q, want %q", output, want)
+	}
+
+	output, err = runCLI(t, "get", "-url", url, "requests-hour")
+	if err != nil {
+		t.Fatalf("get requests hour: %v", err)
+	}
+
+	if output != "3600\n" {
+		t.Fatalf("invalid output: %q", output)
+	}
+
+	if _, err := runCLI(t, "scenario", "-url", url, "start", filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatalf("no error returned for missing scenario")
+	}
+}
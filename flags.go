@@ -0,0 +1,326 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/accesslog"
+	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/cardinality"
+	"github.com/francescomari/metrics-generator/internal/configfile"
+	"github.com/francescomari/metrics-generator/internal/events"
+	"github.com/francescomari/metrics-generator/internal/health"
+	"github.com/francescomari/metrics-generator/internal/stats"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+const envPrefix = "METRICS_GENERATOR_"
+
+const (
+	selfMetricsCombined = "combined"
+	selfMetricsSeparate = "separate"
+)
+
+// parseFlags initializes the settings from the command line arguments, the
+// environment and the configuration file, in this order of precedence. Every
+// flag can be set through an environment variable whose name is returned by
+// envName, and through a key with the same name as the flag in the
+// configuration file. Settings not set anywhere keep their default value.
+func (g *metricsGenerator) parseFlags(args []string, errorHandling flag.ErrorHandling) error {
+	flags := flag.NewFlagSet("metrics-generator", errorHandling)
+
+	flags.StringVar(&g.configFile, "config-file", "", "Path to a YAML configuration file")
+	flags.DurationVar(&g.configCheckInterval, "config-check-interval", 10*time.Second, "How often to check the configuration file for changes, 0 to disable")
+	flags.StringVar(&g.address, "addr", ":8080", "The address to listen to, or unix:<path> for a Unix socket")
+	flags.StringVar(&g.adminAddress, "admin-addr", "", "If set, serve the admin API on this address, or unix:<path> for a Unix socket, and only the metrics on -addr")
+	flags.StringVar(&g.grpcAddress, "grpc-addr", "", "If set, serve the gRPC control-plane service on this address, or unix:<path> for a Unix socket")
+	flags.IntVar(&g.eventsBufferSize, "events-buffer-size", events.DefaultBufferSize, "Number of events buffered for every client of the events endpoint before dropping events")
+	flags.DurationVar(&g.statsRetention, "stats-retention", stats.DefaultRetention, "How long the generated requests are kept to compute statistics")
+	g.statsWindows = append(durationList(nil), api.DefaultStatsWindows...)
+	flags.Var(&g.statsWindows, "stats-windows", "Comma-separated list of the windows reported by the statistics endpoint")
+	flags.DurationVar(&g.readyMaxLag, "ready-max-lag", health.DefaultReadyMaxLag, "How late the generator can be before the readiness probe fails")
+	flags.DurationVar(&g.liveMaxLag, "live-max-lag", health.DefaultLiveMaxLag, "How late the generator can be before the liveness probe fails")
+	flags.StringVar(&g.selfMetrics, "self-metrics", selfMetricsCombined, "Where to serve the metrics about the generator itself: combined, together with the synthetic metrics on /metrics, or separate, on /-/metrics")
+	flags.StringVar(&g.tls.CertFile, "tls-cert-file", "", "Path to the TLS certificate, reloaded when it changes")
+	flags.StringVar(&g.tls.KeyFile, "tls-key-file", "", "Path to the TLS private key, reloaded when it changes")
+	flags.BoolVar(&g.tls.SelfSigned, "tls-self-signed", false, "Serve TLS with a generated self-signed certificate for localhost")
+	flags.StringVar(&g.tls.ClientCAFile, "tls-client-ca-file", "", "Path to the CA certificates used to verify client certificates")
+	flags.StringVar(&g.tls.ClientAuth, "tls-client-auth", "", "Client certificate policy: none, request, require, verify-if-given or require-and-verify (default require-and-verify if a client CA is set, none otherwise)")
+	flags.StringVar(&g.tls.MinVersion, "tls-min-version", "1.2", "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3")
+	flags.StringVar(&g.authFile, "auth-file", "", "Path to a YAML file with the credentials required to change the configuration")
+	g.logLevel.Set("info")
+	flags.Var(&g.logLevel, "log-level", "Only log messages with the given severity or above: debug, info, warn or error")
+	g.logFormat.Set("logfmt")
+	flags.Var(&g.logFormat, "log-format", "Output format of log messages: logfmt or json")
+	flags.BoolVar(&g.logRequests, "log-requests", false, "Log a line at debug level for every simulated request")
+	flags.StringVar(&g.restartLabel, "restart-label", "", "If set, add a label with this name to the synthetic metrics, whose value is the start time of the simulated process and changes at every simulated restart")
+	flags.IntVar(&g.cardinalitySeries, "cardinality-series", 0, "Number of series with a unique label value created to simulate a cardinality explosion")
+	flags.Float64Var(&g.cardinalityChurnRate, "cardinality-churn-rate", 0, "Number of cardinality series retired and replaced by new ones every second")
+	flags.StringVar(&g.cardinalityLabel, "cardinality-label", cardinality.DefaultLabel, "Name of the label with unique values of the cardinality series")
+	flags.BoolVar(&g.summary, "summary", false, "Also expose the request duration as a summary, observing the same requests as the histogram")
+	g.summaryObjectives = objectiveList{{0.5, 0.05}, {0.9, 0.01}, {0.99, 0.001}}
+	flags.Var(&g.summaryObjectives, "summary-objectives", "Comma-separated list of quantile:error objectives of the summary, or an empty string for no quantiles")
+	flags.DurationVar(&g.summaryMaxAge, "summary-max-age", prometheus.DefMaxAge, "How long observations are kept by the summary to compute quantiles")
+	flags.IntVar(&g.summaryAgeBuckets, "summary-age-buckets", prometheus.DefAgeBuckets, "Number of buckets used to phase out observations older than -summary-max-age")
+	flags.Float64Var(&g.exemplarSamplingRate, "exemplar-sampling-rate", 1, "Fraction of the simulated requests whose observations carry an exemplar with a synthetic trace ID, between 0 and 1")
+	flags.StringVar(&g.otlpEndpoint, "otlp-endpoint", "", "If set, export a synthetic trace for every simulated request via OTLP over gRPC to this address")
+	flags.BoolVar(&g.otlpInsecure, "otlp-insecure", false, "Connect to the OTLP endpoint without TLS")
+	flags.StringVar(&g.otlpServiceName, "otlp-service-name", "metrics-generator", "Service name of the synthetic traces")
+	flags.BoolVar(&g.traceChildSpans, "trace-child-spans", true, "Add child spans for fake cache and database calls to the synthetic traces")
+	flags.StringVar(&g.accessLog, "access-log", "", "If set, write an access-log line for every simulated request to stdout, syslog, syslog://<host:port> over UDP, syslog+tcp://<host:port>, or the file at this path")
+	flags.StringVar(&g.accessLogFormat, "access-log-format", accesslog.FormatCombined, "Format of the access log: combined, json or logfmt")
+	flags.Int64Var(&g.accessLogMaxSize, "access-log-max-size", 100, "Size in megabytes beyond which the access-log file is rotated, 0 to disable rotation")
+	flags.IntVar(&g.accessLogMaxBackups, "access-log-max-backups", 5, "Number of rotated access-log files to keep")
+	flags.IntVar(&g.minDuration, "duration-min", 1, "Minimum request duration")
+	flags.IntVar(&g.maxDuration, "duration-max", 10, "Maximum request duration")
+	flags.IntVar(&g.reqHour, "requests-hour", 1000, "Metric generation rate")
+	flags.Float64Var(&g.errorsPercentage, "errors-percentage", 10, "Which percentage of the requests will fail")
+
+	flags.VisitAll(func(f *flag.Flag) {
+		f.Usage = fmt.Sprintf("%s [$%s]", f.Usage, envName(f.Name))
+	})
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: metrics-generator [serve] [flags]\n\nFlags:\n")
+		flags.PrintDefaults()
+		printCLIUsage(flags.Output())
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	g.args = args
+
+	if err := applyEnv(flags); err != nil {
+		return fmt.Errorf("environment: %v", err)
+	}
+
+	if g.configFile != "" {
+		if err := g.applyConfigFile(flags); err != nil {
+			return fmt.Errorf("config file: %v", err)
+		}
+	}
+
+	if err := g.tls.Validate(); err != nil {
+		return fmt.Errorf("tls: %v", err)
+	}
+
+	if g.selfMetrics != selfMetricsCombined && g.selfMetrics != selfMetricsSeparate {
+		return fmt.Errorf("invalid self-metrics mode %q", g.selfMetrics)
+	}
+
+	if !model.LabelName(g.cardinalityLabel).IsValid() {
+		return fmt.Errorf("invalid cardinality label %q", g.cardinalityLabel)
+	}
+
+	if g.restartLabel != "" && !model.LabelName(g.restartLabel).IsValid() {
+		return fmt.Errorf("invalid restart label %q", g.restartLabel)
+	}
+
+	if g.exemplarSamplingRate < 0 || g.exemplarSamplingRate > 1 {
+		return fmt.Errorf("exemplar sampling rate must be between 0 and 1")
+	}
+
+	if !accesslog.ValidFormat(g.accessLogFormat) {
+		return fmt.Errorf("invalid access log format %q", g.accessLogFormat)
+	}
+
+	if g.accessLogMaxSize < 0 {
+		return fmt.Errorf("access log max size must not be negative")
+	}
+
+	if g.accessLogMaxBackups < 0 {
+		return fmt.Errorf("access log max backups must not be negative")
+	}
+
+	if g.summaryMaxAge <= 0 {
+		return fmt.Errorf("summary max age must be positive")
+	}
+
+	if g.summaryAgeBuckets <= 0 {
+		return fmt.Errorf("summary age buckets must be positive")
+	}
+
+	return nil
+}
+
+// envName returns the name of the environment variable for the flag with the
+// given name.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func applyEnv(flags *flag.FlagSet) error {
+	explicit := explicitFlags(flags)
+
+	var err error
+
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+
+		name := envName(f.Name)
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("variable %s: %v", name, setErr)
+		}
+	})
+
+	return err
+}
+
+func (g *metricsGenerator) applyConfigFile(flags *flag.FlagSet) error {
+	settings, err := configfile.Load(g.configFile)
+	if err != nil {
+		return err
+	}
+
+	explicit := explicitFlags(flags)
+
+	for name, value := range settings {
+		if name == "config-file" || flags.Lookup(name) == nil {
+			return fmt.Errorf("unknown setting %q", name)
+		}
+
+		if explicit[name] {
+			continue
+		}
+
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("setting %q: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// explicitFlags returns the names of the flags that have been set, either on
+// the command line or through a call to Set.
+func explicitFlags(flags *flag.FlagSet) map[string]bool {
+	explicit := make(map[string]bool)
+
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	return explicit
+}
+
+// durationList is a flag holding a comma-separated list of durations. Setting
+// the flag replaces the whole list.
+type durationList []time.Duration
+
+func (l *durationList) String() string {
+	var values []string
+
+	for _, d := range *l {
+		values = append(values, d.String())
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (l *durationList) Set(value string) error {
+	var list durationList
+
+	for _, v := range strings.Split(value, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return err
+		}
+
+		if d <= 0 {
+			return fmt.Errorf("duration %v is not positive", d)
+		}
+
+		list = append(list, d)
+	}
+
+	*l = list
+
+	return nil
+}
+
+// objective is a quantile of a summary and its allowed absolute error.
+type objective struct {
+	quantile float64
+	error    float64
+}
+
+// objectiveList is a flag holding a comma-separated list of objectives in the
+// form quantile:error. Setting the flag replaces the whole list.
+type objectiveList []objective
+
+func (l *objectiveList) String() string {
+	var values []string
+
+	for _, o := range *l {
+		values = append(values, fmt.Sprintf("%v:%v", o.quantile, o.error))
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (l *objectiveList) Set(value string) error {
+	var list objectiveList
+
+	if strings.TrimSpace(value) == "" {
+		*l = list
+		return nil
+	}
+
+	for _, v := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(v), ":")
+		if len(parts) != 2 {
+			return fmt.Errorf("objective %q is not in the form quantile:error", v)
+		}
+
+		quantile, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return err
+		}
+
+		if quantile < 0 || quantile > 1 {
+			return fmt.Errorf("quantile %v is not between 0 and 1", quantile)
+		}
+
+		e, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return err
+		}
+
+		if e < 0 || e > 1 {
+			return fmt.Errorf("error %v is not between 0 and 1", e)
+		}
+
+		list = append(list, objective{quantile, e})
+	}
+
+	*l = list
+
+	return nil
+}
+
+// Objectives returns the objectives in the form expected by
+// prometheus.SummaryOpts.
+func (l objectiveList) Objectives() map[float64]float64 {
+	objectives := make(map[float64]float64, len(l))
+
+	for _, o := range l {
+		objectives[o.quantile] = o.error
+	}
+
+	return objectives
+}
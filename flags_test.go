@@ -0,0 +1,175 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseFlagsPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	content := "addr: file\nduration-min: 3\nduration-max: 30\nrequests-hour: 300\n"
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	t.Setenv("METRICS_GENERATOR_CONFIG_FILE", path)
+	t.Setenv("METRICS_GENERATOR_ADDR", "env")
+	t.Setenv("METRICS_GENERATOR_DURATION_MIN", "2")
+
+	var g metricsGenerator
+
+	if err := g.parseFlags([]string{"-addr", "flag"}, flag.ContinueOnError); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+
+	if g.address != "flag" {
+		t.Errorf("invalid address: got %q, want %q", g.address, "flag")
+	}
+	if g.minDuration != 2 {
+		t.Errorf("invalid minimum duration: got %d, want %d", g.minDuration, 2)
+	}
+	if g.maxDuration != 30 {
+		t.Errorf("invalid maximum duration: got %d, want %d", g.maxDuration, 30)
+	}
+	if g.errorsPercentage != 10 {
+		t.Errorf("invalid errors percentage: got %v, want %v", g.errorsPercentage, 10)
+	}
+}
+
+func TestParseFlagsInvalidEnv(t *testing.T) {
+	t.Setenv("METRICS_GENERATOR_DURATION_MIN", "boom")
+
+	var g metricsGenerator
+
+	if err := g.parseFlags(nil, flag.ContinueOnError); err == nil {
+		t.Fatalf("no error returned")
+	}
+}
+
+func TestParseFlagsUnknownSetting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	if err := os.WriteFile(path, []byte("boom: 1\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	var g metricsGenerator
+
+	if err := g.parseFlags([]string{"-config-file", path}, flag.ContinueOnError); err == nil {
+		t.Fatalf("no error returned")
+	}
+}
+
+func TestParseFlagsInvalidSelfMetrics(t *testing.T) {
+	var g metricsGenerator
+
+	if err := g.parseFlags([]string{"-self-metrics", "boom"}, flag.ContinueOnError); err == nil {
+		t.Fatalf("no error returned")
+	}
+}
+
+func TestParseFlagsClientCAWithoutTLS(t *testing.T) {
+	var g metricsGenerator
+
+	if err := g.parseFlags([]string{"-tls-client-ca-file", "ca.pem"}, flag.ContinueOnError); err == nil {
+		t.Fatalf("no error returned")
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if got, want := envName("duration-min"), "METRICS_GENERATOR_DURATION_MIN"; got != want {
+		t.Fatalf("invalid name: got %q, want %q", got, want)
+	}
+}
+
+func TestDurationList(t *testing.T) {
+	list := durationList{time.Hour}
+
+	if err := list.Set("1m, 30s"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	if len(list) != 2 || list[0] != time.Minute || list[1] != 30*time.Second {
+		t.Fatalf("invalid list: %v", list)
+	}
+
+	for _, value := range []string{"", "boom", "1m,-1s"} {
+		if err := list.Set(value); err == nil {
+			t.Errorf("no error returned for %q", value)
+		}
+	}
+}
+
+func TestObjectiveList(t *testing.T) {
+	var list objectiveList
+
+	if err := list.Set("0.5:0.05, 0.99:0.001"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	objectives := list.Objectives()
+
+	if len(objectives) != 2 || objectives[0.5] != 0.05 || objectives[0.99] != 0.001 {
+		t.Fatalf("invalid objectives: %v", objectives)
+	}
+
+	if got, want := list.String(), "0.5:0.05,0.99:0.001"; got != want {
+		t.Fatalf("invalid string: got %q, want %q", got, want)
+	}
+
+	if err := list.Set(""); err != nil {
+		t.Fatalf("set empty: %v", err)
+	}
+
+	if len(list) != 0 {
+		t.Fatalf("list not empty: %v", list)
+	}
+
+	for _, value := range []string{"boom", "0.5", "0.5:boom", "1.5:0.01", "0.5:-1"} {
+		if err := list.Set(value); err == nil {
+			t.Errorf("no error returned for %q", value)
+		}
+	}
+}
+
+func TestParseFlagsInvalidSummary(t *testing.T) {
+	for _, args := range [][]string{
+		{"-summary-max-age", "0s"},
+		{"-summary-age-buckets", "0"},
+	} {
+		var g metricsGenerator
+
+		if err := g.parseFlags(args, flag.ContinueOnError); err == nil {
+			t.Errorf("no error returned for %v", args)
+		}
+	}
+}
+
+func TestParseFlagsInvalidExemplarSamplingRate(t *testing.T) {
+	for _, value := range []string{"-0.1", "1.1"} {
+		var g metricsGenerator
+
+		if err := g.parseFlags([]string{"-exemplar-sampling-rate", value}, flag.ContinueOnError); err == nil {
+			t.Errorf("no error returned for %q", value)
+		}
+	}
+}
+
+func TestParseFlagsInvalidAccessLog(t *testing.T) {
+	for _, args := range [][]string{
+		{"-access-log-format", "boom"},
+		{"-access-log-max-size", "-1"},
+		{"-access-log-max-backups", "-1"},
+	} {
+		var g metricsGenerator
+
+		if err := g.parseFlags(args, flag.ContinueOnError); err == nil {
+			t.Errorf("no error returned for %v", args)
+		}
+	}
+}
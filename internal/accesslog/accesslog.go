@@ -0,0 +1,147 @@
+// Package accesslog writes an access-log line for every simulated request, so
+// that metrics derived from logs can be compared with the synthetic metrics.
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Formats of the access log.
+const (
+	// FormatCombined is the Apache combined log format, followed by the
+	// duration of the request in seconds like the $request_time of nginx.
+	FormatCombined = "combined"
+
+	// FormatJSON writes a JSON object per line.
+	FormatJSON = "json"
+
+	// FormatLogfmt writes a line of logfmt key-value pairs.
+	FormatLogfmt = "logfmt"
+)
+
+// Formats are the supported formats.
+var Formats = []string{FormatCombined, FormatJSON, FormatLogfmt}
+
+// ValidFormat returns whether format is one of Formats.
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The simulated requests are all served by the same fake endpoint.
+const (
+	Method = http.MethodGet
+	Path   = "/api/v1/items"
+)
+
+const (
+	remoteAddr   = "127.0.0.1"
+	userAgent    = "metrics-generator"
+	combinedTime = "02/Jan/2006:15:04:05 -0700"
+)
+
+// Entry is an access-log line of a simulated request.
+type Entry struct {
+	// Time is when the request completed, which is when the synthetic
+	// metrics observe it.
+	Time     time.Time
+	Method   string
+	Path     string
+	Status   int
+	Duration float64
+
+	// TraceID is the trace ID of the exemplar of the request, if any.
+	TraceID string
+}
+
+// NewEntry returns the entry of a simulated request. Failed requests have
+// status 500, the others status 200.
+func NewEntry(now time.Time, duration float64, failed bool, traceID string) Entry {
+	status := http.StatusOK
+
+	if failed {
+		status = http.StatusInternalServerError
+	}
+
+	return Entry{
+		Time:     now,
+		Method:   Method,
+		Path:     Path,
+		Status:   status,
+		Duration: duration,
+		TraceID:  traceID,
+	}
+}
+
+// Format returns the entry in the given format, terminated by a newline.
+func (e Entry) Format(format string) ([]byte, error) {
+	switch format {
+	case FormatCombined:
+		return []byte(fmt.Sprintf("%s - - [%s] \"%s %s HTTP/1.1\" %d - \"-\" \"%s\" %.3f\n",
+			remoteAddr, e.Time.Format(combinedTime), e.Method, e.Path, e.Status, userAgent, e.Duration)), nil
+	case FormatJSON:
+		data, err := json.Marshal(struct {
+			Time     time.Time `json:"time"`
+			Method   string    `json:"method"`
+			Path     string    `json:"path"`
+			Status   int       `json:"status"`
+			Duration float64   `json:"duration"`
+			TraceID  string    `json:"trace_id,omitempty"`
+		}{e.Time, e.Method, e.Path, e.Status, e.Duration, e.TraceID})
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatLogfmt:
+		line := fmt.Sprintf("time=%s method=%s path=%s status=%d duration=%s",
+			e.Time.Format(time.RFC3339Nano), e.Method, e.Path, e.Status, strconv.FormatFloat(e.Duration, 'f', -1, 64))
+		if e.TraceID != "" {
+			line += " trace_id=" + e.TraceID
+		}
+		return []byte(line + "\n"), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// Logger writes an access-log line for every simulated request.
+type Logger struct {
+	Format string
+
+	// Writer receives every line with a single call to Write.
+	Writer io.Writer
+
+	// Logger, if set, receives the errors writing to Writer.
+	Logger log.Logger
+
+	mu sync.Mutex
+}
+
+// Log writes the access-log line of a simulated request that completed at
+// time now.
+func (l *Logger) Log(now time.Time, duration float64, failed bool, traceID string) {
+	line, err := NewEntry(now, duration, failed, traceID).Format(l.Format)
+	if err == nil {
+		l.mu.Lock()
+		_, err = l.Writer.Write(line)
+		l.mu.Unlock()
+	}
+
+	if err != nil && l.Logger != nil {
+		level.Warn(l.Logger).Log("msg", "Failed to write access log", "err", err)
+	}
+}
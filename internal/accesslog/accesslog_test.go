@@ -0,0 +1,77 @@
+package accesslog_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/accesslog"
+)
+
+func TestEntryFormat(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		format   string
+		failed   bool
+		traceID  string
+		expected string
+	}{
+		{
+			format:   accesslog.FormatCombined,
+			expected: `127.0.0.1 - - [01/Jun/2021:10:00:00 +0000] "GET /api/v1/items HTTP/1.1" 200 - "-" "metrics-generator" 2.500` + "\n",
+		},
+		{
+			format:   accesslog.FormatJSON,
+			failed:   true,
+			traceID:  "abc",
+			expected: `{"time":"2021-06-01T10:00:00Z","method":"GET","path":"/api/v1/items","status":500,"duration":2.5,"trace_id":"abc"}` + "\n",
+		},
+		{
+			format:   accesslog.FormatLogfmt,
+			expected: "time=2021-06-01T10:00:00Z method=GET path=/api/v1/items status=200 duration=2.5\n",
+		},
+		{
+			format:   accesslog.FormatLogfmt,
+			failed:   true,
+			traceID:  "abc",
+			expected: "time=2021-06-01T10:00:00Z method=GET path=/api/v1/items status=500 duration=2.5 trace_id=abc\n",
+		},
+	}
+
+	for _, test := range tests {
+		line, err := accesslog.NewEntry(now, 2.5, test.failed, test.traceID).Format(test.format)
+		if err != nil {
+			t.Fatalf("format %s: %v", test.format, err)
+		}
+
+		if string(line) != test.expected {
+			t.Errorf("invalid %s line: got %q, want %q", test.format, line, test.expected)
+		}
+	}
+}
+
+func TestEntryFormatUnknown(t *testing.T) {
+	if _, err := accesslog.NewEntry(time.Now(), 1, false, "").Format("boom"); err == nil {
+		t.Fatalf("no error returned")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buffer bytes.Buffer
+
+	logger := accesslog.Logger{
+		Format: accesslog.FormatLogfmt,
+		Writer: &buffer,
+	}
+
+	logger.Log(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC), 1, false, "")
+	logger.Log(time.Date(2021, 6, 1, 10, 0, 1, 0, time.UTC), 2, true, "")
+
+	expected := "time=2021-06-01T10:00:00Z method=GET path=/api/v1/items status=200 duration=1\n" +
+		"time=2021-06-01T10:00:01Z method=GET path=/api/v1/items status=500 duration=2\n"
+
+	if buffer.String() != expected {
+		t.Fatalf("invalid log: got %q, want %q", buffer.String(), expected)
+	}
+}
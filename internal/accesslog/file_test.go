@@ -0,0 +1,103 @@
+package accesslog_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/francescomari/metrics-generator/internal/accesslog"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+
+	return string(data)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	file := accesslog.RotatingFile{
+		Path:       path,
+		MaxSize:    4,
+		MaxBackups: 2,
+	}
+
+	defer file.Close()
+
+	for _, line := range []string{"a\n", "b\n", "c\n", "d\n", "e\n", "f\n", "g\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if got, want := readFile(t, path), "g\n"; got != want {
+		t.Fatalf("invalid file: got %q, want %q", got, want)
+	}
+
+	if got, want := readFile(t, path+".1"), "e\nf\n"; got != want {
+		t.Fatalf("invalid first backup: got %q, want %q", got, want)
+	}
+
+	if got, want := readFile(t, path+".2"), "c\nd\n"; got != want {
+		t.Fatalf("invalid second backup: got %q, want %q", got, want)
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("too many backups: %v", err)
+	}
+}
+
+func TestRotatingFileNoBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	file := accesslog.RotatingFile{
+		Path:    path,
+		MaxSize: 4,
+	}
+
+	defer file.Close()
+
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if got, want := readFile(t, path), "c\n"; got != want {
+		t.Fatalf("invalid file: got %q, want %q", got, want)
+	}
+
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Fatalf("backup created: %v", err)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	writer, err := accesslog.Open(path, 0, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	defer writer.Close()
+
+	// The file is created before the first write.
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("file not created: %v", err)
+	}
+}
+
+func TestOpenFileUnwritable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "access.log")
+
+	if _, err := accesslog.Open(path, 0, 0); err == nil {
+		t.Fatalf("no error returned")
+	}
+}
@@ -0,0 +1,44 @@
+package accesslog
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// Open returns the writer for a destination of the access log, which is one
+// of:
+//
+//   - "stdout", the standard output;
+//   - "syslog", the local syslog daemon;
+//   - "syslog://<host:port>" or "syslog+tcp://<host:port>", a remote syslog
+//     daemon over UDP or TCP;
+//   - any other value, the path of a RotatingFile with the given maximum size
+//     and number of backups, which is opened immediately.
+func Open(destination string, maxSize int64, maxBackups int) (io.WriteCloser, error) {
+	switch {
+	case destination == "stdout":
+		return nopCloser{os.Stdout}, nil
+	case destination == "syslog":
+		return DialSyslog("", "")
+	case strings.HasPrefix(destination, "syslog://"):
+		return DialSyslog("udp", strings.TrimPrefix(destination, "syslog://"))
+	case strings.HasPrefix(destination, "syslog+tcp://"):
+		return DialSyslog("tcp", strings.TrimPrefix(destination, "syslog+tcp://"))
+	default:
+		file, err := OpenRotatingFile(destination, maxSize, maxBackups)
+		if err != nil {
+			return nil, err
+		}
+
+		return file, nil
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
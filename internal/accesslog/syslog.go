@@ -0,0 +1,16 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package accesslog
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// DialSyslog connects to a syslog daemon, and returns a writer sending every
+// write as a message with severity info. If network and address are empty,
+// the local daemon is used.
+func DialSyslog(network, address string) (io.WriteCloser, error) {
+	return syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, userAgent)
+}
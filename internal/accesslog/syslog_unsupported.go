@@ -0,0 +1,15 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package accesslog
+
+import (
+	"errors"
+	"io"
+)
+
+// DialSyslog returns an error, because syslog is not supported on this
+// platform.
+func DialSyslog(network, address string) (io.WriteCloser, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
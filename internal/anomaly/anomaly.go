@@ -0,0 +1,314 @@
+// Package anomaly manages anomalies applied on top of the baseline behaviour
+// of the generator. Every anomaly expires automatically after its TTL.
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Anomaly types.
+const (
+	// TypeLatencySpike multiplies the duration of the simulated requests by
+	// the magnitude.
+	TypeLatencySpike = "latency-spike"
+
+	// TypeTrafficDrop stops the simulated requests.
+	TypeTrafficDrop = "traffic-drop"
+
+	// TypeTrafficBurst multiplies the rate of the simulated requests by the
+	// magnitude.
+	TypeTrafficBurst = "traffic-burst"
+
+	// TypeDrift makes the drift gauge grow by the magnitude every second,
+	// like the memory usage of a process with a leak.
+	TypeDrift = "drift"
+
+	// TypeFlapping alternates between a bad state, in which the magnitude is
+	// the percentage of failed requests, and the baseline. Every period
+	// starts in the bad state.
+	TypeFlapping = "flapping"
+)
+
+// Bounds of the magnitude of TypeTrafficBurst. A smaller magnitude would stop
+// the generator for longer than any sensible TTL, and a larger one would make
+// it spin. The combined magnitude of overlapping bursts is kept within the
+// same bounds.
+const (
+	minTrafficBurst = 0.01
+	maxTrafficBurst = 1000
+)
+
+// maxLatencySpike is the maximum magnitude of TypeLatencySpike, for a single
+// anomaly and for overlapping ones combined. It keeps the simulated durations
+// within the range of a time.Duration.
+const maxLatencySpike = 1000
+
+// Types are the supported anomaly types.
+var Types = []string{TypeLatencySpike, TypeTrafficDrop, TypeTrafficBurst, TypeDrift, TypeFlapping}
+
+// Spec describes an anomaly.
+type Spec struct {
+	Type string
+
+	// Magnitude is the strength of the anomaly, whose meaning depends on the
+	// type. It is ignored by TypeTrafficDrop, and defaults to 100 for
+	// TypeFlapping.
+	Magnitude float64
+
+	// TTL is how long after being added the anomaly expires.
+	TTL time.Duration
+
+	// Period, if set, makes the anomaly recurring. The anomaly is active for
+	// Duration at the beginning of every period. If zero, the anomaly is
+	// active until it expires.
+	Period time.Duration
+
+	// Duration is how long every occurrence of a recurring anomaly lasts.
+	// For TypeFlapping, it defaults to half of the period.
+	Duration time.Duration
+}
+
+// Validate returns an error if the spec is invalid.
+func (s Spec) Validate() error {
+	switch s.Type {
+	case TypeLatencySpike:
+		if !(s.Magnitude > 0 && s.Magnitude <= maxLatencySpike) {
+			return fmt.Errorf("magnitude must be positive and at most %v", maxLatencySpike)
+		}
+	case TypeDrift:
+		if s.Magnitude <= 0 {
+			return fmt.Errorf("magnitude must be positive")
+		}
+	case TypeTrafficBurst:
+		if !(s.Magnitude >= minTrafficBurst && s.Magnitude <= maxTrafficBurst) {
+			return fmt.Errorf("magnitude must be between %v and %v", minTrafficBurst, maxTrafficBurst)
+		}
+	case TypeFlapping:
+		if s.Magnitude < 0 || s.Magnitude > 100 {
+			return fmt.Errorf("magnitude must be between 0 and 100")
+		}
+
+		if s.Period <= 0 {
+			return fmt.Errorf("period is required")
+		}
+	case TypeTrafficDrop:
+	default:
+		return fmt.Errorf("unknown type %q", s.Type)
+	}
+
+	if s.TTL <= 0 {
+		return fmt.Errorf("TTL must be positive")
+	}
+
+	if s.Period < 0 || s.Duration < 0 {
+		return fmt.Errorf("period and duration must not be negative")
+	}
+
+	if s.Period > 0 && s.Duration > s.Period {
+		return fmt.Errorf("duration must not be greater than the period")
+	}
+
+	if s.Period > 0 && s.Duration == 0 && s.Type != TypeFlapping {
+		return fmt.Errorf("duration is required with a period")
+	}
+
+	return nil
+}
+
+// Anomaly is an anomaly added to a Set.
+type Anomaly struct {
+	Spec
+
+	ID      string
+	Start   time.Time
+	Expires time.Time
+
+	// Active is true if the anomaly is in effect.
+	Active bool
+}
+
+// activeSince returns whether the anomaly is in effect at time now and, if
+// it is, when the current occurrence started.
+func (a Anomaly) activeSince(now time.Time) (time.Time, bool) {
+	if now.Before(a.Start) || !now.Before(a.Expires) {
+		return time.Time{}, false
+	}
+
+	if a.Period == 0 {
+		return a.Start, true
+	}
+
+	duration := a.Duration
+
+	if duration == 0 {
+		duration = a.Period / 2
+	}
+
+	elapsed := now.Sub(a.Start) % a.Period
+
+	if elapsed >= duration {
+		return time.Time{}, false
+	}
+
+	return now.Add(-elapsed), true
+}
+
+// Effects is the combined effect of the active anomalies.
+type Effects struct {
+	// Latency multiplies the duration of the simulated requests.
+	Latency float64
+
+	// Traffic multiplies the rate of the simulated requests.
+	Traffic float64
+
+	// Drop is true if no request should be simulated.
+	Drop bool
+
+	// ErrorsPercentage, if not negative, replaces the configured percentage
+	// of failed requests.
+	ErrorsPercentage float64
+
+	// Drift is the value added to the drift gauge.
+	Drift float64
+}
+
+// Set is a set of anomalies. The zero value is an empty set, ready to use.
+type Set struct {
+	mu        sync.Mutex
+	lastID    int
+	anomalies []Anomaly
+}
+
+// Add adds an anomaly that starts at time now.
+func (s *Set) Add(spec Spec, now time.Time) (Anomaly, error) {
+	if err := spec.Validate(); err != nil {
+		return Anomaly{}, err
+	}
+
+	if spec.Type == TypeFlapping && spec.Magnitude == 0 {
+		spec.Magnitude = 100
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+
+	a := Anomaly{
+		Spec:    spec,
+		ID:      strconv.Itoa(s.lastID),
+		Start:   now,
+		Expires: now.Add(spec.TTL),
+	}
+
+	s.anomalies = append(s.anomalies, a)
+
+	_, a.Active = a.activeSince(now)
+
+	return a, nil
+}
+
+// Remove removes the anomaly with the given ID, and returns false if it
+// doesn't exist.
+func (s *Set) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, a := range s.anomalies {
+		if a.ID == id {
+			s.anomalies = append(s.anomalies[:i], s.anomalies[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Clear removes every anomaly.
+func (s *Set) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.anomalies = nil
+}
+
+// List returns the anomalies that haven't expired at time now, sorted by
+// start time.
+func (s *Set) List(now time.Time) []Anomaly {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(now)
+
+	list := make([]Anomaly, 0, len(s.anomalies))
+
+	for _, a := range s.anomalies {
+		_, a.Active = a.activeSince(now)
+		list = append(list, a)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Start.Before(list[j].Start)
+	})
+
+	return list
+}
+
+// Effects returns the combined effect of the anomalies active at time now.
+func (s *Set) Effects(now time.Time) Effects {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(now)
+
+	effects := Effects{
+		Latency:          1,
+		Traffic:          1,
+		ErrorsPercentage: -1,
+	}
+
+	for _, a := range s.anomalies {
+		since, ok := a.activeSince(now)
+		if !ok {
+			continue
+		}
+
+		switch a.Type {
+		case TypeLatencySpike:
+			effects.Latency *= a.Magnitude
+		case TypeTrafficDrop:
+			effects.Drop = true
+		case TypeTrafficBurst:
+			effects.Traffic *= a.Magnitude
+		case TypeDrift:
+			effects.Drift += a.Magnitude * now.Sub(since).Seconds()
+		case TypeFlapping:
+			if a.Magnitude > effects.ErrorsPercentage {
+				effects.ErrorsPercentage = a.Magnitude
+			}
+		}
+	}
+
+	effects.Latency = math.Min(effects.Latency, maxLatencySpike)
+	effects.Traffic = math.Max(minTrafficBurst, math.Min(effects.Traffic, maxTrafficBurst))
+
+	return effects
+}
+
+func (s *Set) expire(now time.Time) {
+	n := 0
+
+	for _, a := range s.anomalies {
+		if now.Before(a.Expires) {
+			s.anomalies[n] = a
+			n++
+		}
+	}
+
+	s.anomalies = s.anomalies[:n]
+}
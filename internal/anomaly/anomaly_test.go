@@ -0,0 +1,209 @@
+package anomaly_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/anomaly"
+)
+
+func TestOneShot(t *testing.T) {
+	var set anomaly.Set
+
+	now := time.Unix(1000, 0)
+
+	a, err := set.Add(anomaly.Spec{Type: anomaly.TypeLatencySpike, Magnitude: 10, TTL: time.Minute}, now)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	if !a.Active || a.ID == "" {
+		t.Fatalf("invalid anomaly: %+v", a)
+	}
+
+	if effects := set.Effects(now.Add(30 * time.Second)); effects.Latency != 10 {
+		t.Fatalf("invalid effects: %+v", effects)
+	}
+
+	if effects := set.Effects(now.Add(time.Minute)); effects.Latency != 1 {
+		t.Fatalf("anomaly not expired: %+v", effects)
+	}
+
+	if list := set.List(now); len(list) != 0 {
+		t.Fatalf("expired anomaly listed: %+v", list)
+	}
+}
+
+func TestRecurring(t *testing.T) {
+	var set anomaly.Set
+
+	now := time.Unix(1000, 0)
+
+	spec := anomaly.Spec{
+		Type:      anomaly.TypeTrafficBurst,
+		Magnitude: 5,
+		TTL:       time.Hour,
+		Period:    time.Minute,
+		Duration:  10 * time.Second,
+	}
+
+	if _, err := set.Add(spec, now); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		traffic float64
+	}{
+		{0, 5},
+		{5 * time.Second, 5},
+		{10 * time.Second, 1},
+		{time.Minute, 5},
+		{time.Minute + 30*time.Second, 1},
+	}
+
+	for _, test := range tests {
+		if effects := set.Effects(now.Add(test.elapsed)); effects.Traffic != test.traffic {
+			t.Errorf("invalid traffic after %v: %v", test.elapsed, effects.Traffic)
+		}
+	}
+}
+
+func TestFlapping(t *testing.T) {
+	var set anomaly.Set
+
+	now := time.Unix(1000, 0)
+
+	if _, err := set.Add(anomaly.Spec{Type: anomaly.TypeFlapping, TTL: time.Hour, Period: time.Minute}, now); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	if effects := set.Effects(now.Add(10 * time.Second)); effects.ErrorsPercentage != 100 {
+		t.Fatalf("not in the bad state: %+v", effects)
+	}
+
+	if effects := set.Effects(now.Add(40 * time.Second)); effects.ErrorsPercentage >= 0 {
+		t.Fatalf("not in the good state: %+v", effects)
+	}
+}
+
+func TestDriftAndDrop(t *testing.T) {
+	var set anomaly.Set
+
+	now := time.Unix(1000, 0)
+
+	if _, err := set.Add(anomaly.Spec{Type: anomaly.TypeDrift, Magnitude: 2, TTL: time.Hour}, now); err != nil {
+		t.Fatalf("add drift: %v", err)
+	}
+
+	drop, err := set.Add(anomaly.Spec{Type: anomaly.TypeTrafficDrop, TTL: time.Hour}, now)
+	if err != nil {
+		t.Fatalf("add drop: %v", err)
+	}
+
+	effects := set.Effects(now.Add(time.Minute))
+
+	if effects.Drift != 120 || !effects.Drop {
+		t.Fatalf("invalid effects: %+v", effects)
+	}
+
+	if !set.Remove(drop.ID) {
+		t.Fatalf("anomaly not removed")
+	}
+
+	if set.Remove(drop.ID) {
+		t.Fatalf("anomaly removed twice")
+	}
+
+	if effects := set.Effects(now.Add(time.Minute)); effects.Drop {
+		t.Fatalf("invalid effects: %+v", effects)
+	}
+
+	set.Clear()
+
+	if list := set.List(now); len(list) != 0 {
+		t.Fatalf("anomalies not cleared: %+v", list)
+	}
+}
+
+func TestStackedEffects(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []anomaly.Spec
+		latency float64
+		traffic float64
+	}{
+		{
+			name: "bursts",
+			specs: []anomaly.Spec{
+				{Type: anomaly.TypeTrafficBurst, Magnitude: 1000, TTL: time.Minute},
+				{Type: anomaly.TypeTrafficBurst, Magnitude: 1000, TTL: time.Minute},
+			},
+			latency: 1,
+			traffic: 1000,
+		},
+		{
+			name: "slowdowns",
+			specs: []anomaly.Spec{
+				{Type: anomaly.TypeTrafficBurst, Magnitude: 0.01, TTL: time.Minute},
+				{Type: anomaly.TypeTrafficBurst, Magnitude: 0.01, TTL: time.Minute},
+				{Type: anomaly.TypeTrafficBurst, Magnitude: 0.01, TTL: time.Minute},
+			},
+			latency: 1,
+			traffic: 0.01,
+		},
+		{
+			name: "latency spikes",
+			specs: []anomaly.Spec{
+				{Type: anomaly.TypeLatencySpike, Magnitude: 1000, TTL: time.Minute},
+				{Type: anomaly.TypeLatencySpike, Magnitude: 1000, TTL: time.Minute},
+			},
+			latency: 1000,
+			traffic: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var set anomaly.Set
+
+			now := time.Unix(1000, 0)
+
+			for _, spec := range test.specs {
+				if _, err := set.Add(spec, now); err != nil {
+					t.Fatalf("add: %v", err)
+				}
+			}
+
+			effects := set.Effects(now)
+
+			if effects.Latency != test.latency || effects.Traffic != test.traffic {
+				t.Fatalf("invalid effects: %+v", effects)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []anomaly.Spec{
+		{Type: "unknown", TTL: time.Minute},
+		{Type: anomaly.TypeTrafficDrop},
+		{Type: anomaly.TypeLatencySpike, TTL: time.Minute},
+		{Type: anomaly.TypeLatencySpike, Magnitude: 1e6, TTL: time.Minute},
+		{Type: anomaly.TypeLatencySpike, Magnitude: math.NaN(), TTL: time.Minute},
+		{Type: anomaly.TypeTrafficBurst, Magnitude: 1e-6, TTL: time.Minute},
+		{Type: anomaly.TypeTrafficBurst, Magnitude: 1e9, TTL: time.Minute},
+		{Type: anomaly.TypeTrafficBurst, Magnitude: math.NaN(), TTL: time.Minute},
+		{Type: anomaly.TypeFlapping, TTL: time.Minute},
+		{Type: anomaly.TypeFlapping, Magnitude: 200, TTL: time.Minute, Period: time.Second},
+		{Type: anomaly.TypeTrafficDrop, TTL: time.Minute, Period: time.Second},
+		{Type: anomaly.TypeTrafficDrop, TTL: time.Minute, Period: time.Second, Duration: time.Minute},
+	}
+
+	for _, spec := range invalid {
+		if err := spec.Validate(); err == nil {
+			t.Errorf("no error returned for %+v", spec)
+		}
+	}
+}
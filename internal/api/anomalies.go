@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/anomaly"
+	"github.com/gorilla/mux"
+)
+
+type Anomalies interface {
+	Add(spec anomaly.Spec, now time.Time) (anomaly.Anomaly, error)
+	Remove(id string) bool
+	Clear()
+	List(now time.Time) []anomaly.Anomaly
+}
+
+func (h *Handler) setupAnomaliesHandlers(router *mux.Router) {
+	if h.Anomalies == nil {
+		return
+	}
+
+	sub := router.
+		Path("/-/anomalies").
+		Subrouter()
+
+	sub.
+		Methods(http.MethodGet).
+		HandlerFunc(h.handleListAnomalies)
+
+	sub.
+		Methods(http.MethodPost).
+		HandlerFunc(h.handleAddAnomaly)
+
+	sub.
+		Methods(http.MethodDelete).
+		HandlerFunc(h.handleClearAnomalies)
+
+	router.
+		Methods(http.MethodDelete).
+		Path("/-/anomalies/{id}").
+		HandlerFunc(h.handleRemoveAnomaly)
+}
+
+// anomalySpec is the JSON representation of anomaly.Spec. Durations are in
+// the format accepted by time.ParseDuration.
+type anomalySpec struct {
+	Type      string  `json:"type"`
+	Magnitude float64 `json:"magnitude,omitempty"`
+	TTL       string  `json:"ttl"`
+	Period    string  `json:"period,omitempty"`
+	Duration  string  `json:"duration,omitempty"`
+}
+
+func (s anomalySpec) toSpec() (anomaly.Spec, error) {
+	spec := anomaly.Spec{
+		Type:      s.Type,
+		Magnitude: s.Magnitude,
+	}
+
+	durations := []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"ttl", s.TTL, &spec.TTL},
+		{"period", s.Period, &spec.Period},
+		{"duration", s.Duration, &spec.Duration},
+	}
+
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+
+		value, err := time.ParseDuration(d.value)
+		if err != nil {
+			return anomaly.Spec{}, fmt.Errorf("invalid %s: %v", d.name, err)
+		}
+
+		*d.dst = value
+	}
+
+	return spec, nil
+}
+
+type anomalyData struct {
+	anomalySpec
+	ID      string    `json:"id"`
+	Start   time.Time `json:"start"`
+	Expires time.Time `json:"expires"`
+	Active  bool      `json:"active"`
+}
+
+func newAnomalyData(a anomaly.Anomaly) anomalyData {
+	formatDuration := func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return d.String()
+	}
+
+	return anomalyData{
+		anomalySpec: anomalySpec{
+			Type:      a.Type,
+			Magnitude: a.Magnitude,
+			TTL:       a.TTL.String(),
+			Period:    formatDuration(a.Period),
+			Duration:  formatDuration(a.Duration),
+		},
+		ID:      a.ID,
+		Start:   a.Start,
+		Expires: a.Expires,
+		Active:  a.Active,
+	}
+}
+
+func (h *Handler) handleListAnomalies(w http.ResponseWriter, r *http.Request) {
+	data := []anomalyData{}
+
+	for _, a := range h.Anomalies.List(time.Now()) {
+		data = append(data, newAnomalyData(a))
+	}
+
+	h.writeJSON(w, r, data)
+}
+
+// handleAddAnomaly adds the anomaly in the JSON body of the request, and
+// responds with the anomaly and its ID.
+func (h *Handler) handleAddAnomaly(w http.ResponseWriter, r *http.Request) {
+	var body anomalySpec
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&body); err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse anomaly: %v", err)
+		return
+	}
+
+	spec, err := body.toSpec()
+	if err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse anomaly: %v", err)
+		return
+	}
+
+	a, err := h.Anomalies.Add(spec, time.Now())
+	if err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "add anomaly: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/-/anomalies/"+a.ID)
+	w.WriteHeader(http.StatusCreated)
+
+	h.writeJSON(w, r, newAnomalyData(a))
+}
+
+func (h *Handler) handleRemoveAnomaly(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if !h.Anomalies.Remove(id) {
+		h.httpError(w, r, http.StatusNotFound, "anomaly %q not found", id)
+		return
+	}
+
+	fmt.Fprintln(w, "OK")
+}
+
+func (h *Handler) handleClearAnomalies(w http.ResponseWriter, r *http.Request) {
+	h.Anomalies.Clear()
+	fmt.Fprintln(w, "OK")
+}
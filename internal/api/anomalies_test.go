@@ -0,0 +1,78 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/francescomari/metrics-generator/internal/anomaly"
+	"github.com/francescomari/metrics-generator/internal/api"
+)
+
+func TestHandlerAnomalies(t *testing.T) {
+	var anomalies anomaly.Set
+
+	handler := api.Handler{Anomalies: &anomalies}
+
+	body := `{"type": "latency-spike", "magnitude": 10, "ttl": "10m", "period": "1m", "duration": "10s"}`
+
+	response := doRequestWithBody(&handler, http.MethodPost, "/-/anomalies", strings.NewReader(body))
+	checkStatusCode(t, response, http.StatusCreated)
+
+	var created struct {
+		ID     string `json:"id"`
+		Type   string `json:"type"`
+		TTL    string `json:"ttl"`
+		Active bool   `json:"active"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&created); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if created.ID == "" || created.Type != anomaly.TypeLatencySpike || created.TTL != "10m0s" || !created.Active {
+		t.Fatalf("invalid anomaly: %+v", created)
+	}
+
+	if location := response.Header.Get("Location"); location != "/-/anomalies/"+created.ID {
+		t.Fatalf("invalid location: %v", location)
+	}
+
+	response = doRequest(&handler, http.MethodGet, "/-/anomalies")
+	checkStatusCode(t, response, http.StatusOK)
+
+	var list []map[string]interface{}
+
+	if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if len(list) != 1 || list[0]["id"] != created.ID {
+		t.Fatalf("invalid list: %+v", list)
+	}
+
+	checkStatusCode(t, doRequest(&handler, http.MethodDelete, "/-/anomalies/"+created.ID), http.StatusOK)
+	checkStatusCode(t, doRequest(&handler, http.MethodDelete, "/-/anomalies/"+created.ID), http.StatusNotFound)
+
+	response = doRequest(&handler, http.MethodGet, "/-/anomalies")
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, "[]\n")
+}
+
+func TestHandlerAnomaliesInvalid(t *testing.T) {
+	bodies := []string{
+		`{"type": "unknown", "ttl": "1m"}`,
+		`{"type": "traffic-drop"}`,
+		`{"type": "traffic-drop", "ttl": "soon"}`,
+		`{"type": "traffic-drop", "ttl": "1m", "unknown": 1}`,
+		`not json`,
+	}
+
+	for _, body := range bodies {
+		handler := api.Handler{Anomalies: &anomaly.Set{}}
+
+		response := doRequestWithBody(&handler, http.MethodPost, "/-/anomalies", strings.NewReader(body))
+		checkStatusCode(t, response, http.StatusBadRequest)
+	}
+}
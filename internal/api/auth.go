@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/francescomari/metrics-generator/internal/auth"
+	"github.com/go-kit/kit/log/level"
+)
+
+func (h *Handler) requireAdminForChanges(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		role, err := h.Authenticator.Authenticate(r)
+
+		switch {
+		case err != nil:
+			h.rejectUnauthorized(w, r, http.StatusUnauthorized, err.Error())
+		case role == auth.RoleNone:
+			h.rejectUnauthorized(w, r, http.StatusUnauthorized, "missing credentials")
+		case role != auth.RoleAdmin:
+			h.rejectUnauthorized(w, r, http.StatusForbidden, "role "+role.String()+" is not allowed")
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (h *Handler) rejectUnauthorized(w http.ResponseWriter, r *http.Request, code int, reason string) {
+	level.Warn(h.logger()).Log("msg", "Rejected unauthorized request", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "reason", reason)
+
+	if h.Unauthorized != nil {
+		h.Unauthorized.Inc()
+	}
+
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="metrics-generator"`)
+	}
+
+	http.Error(w, http.StatusText(code), code)
+}
@@ -0,0 +1,102 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/auth"
+)
+
+type mockAuthenticator struct {
+	role auth.Role
+	err  error
+}
+
+func (a mockAuthenticator) Authenticate(r *http.Request) (auth.Role, error) {
+	return a.role, a.err
+}
+
+type mockCounter struct {
+	count int
+}
+
+func (c *mockCounter) Inc() {
+	c.count++
+}
+
+func TestHandlerAuthGetIsOpen(t *testing.T) {
+	config := mockConfig{
+		doErrorsPercentage: func() float64 {
+			return 12
+		},
+	}
+
+	handler := api.Handler{
+		Config:        config,
+		Authenticator: mockAuthenticator{role: auth.RoleNone},
+	}
+
+	response := doGetErrorsPercentageRequest(&handler)
+
+	checkStatusCode(t, response, http.StatusOK)
+}
+
+func TestHandlerAuthChanges(t *testing.T) {
+	tests := []struct {
+		name         string
+		role         auth.Role
+		err          error
+		status       int
+		unauthorized int
+	}{
+		{
+			name:         "anonymous",
+			role:         auth.RoleNone,
+			status:       http.StatusUnauthorized,
+			unauthorized: 1,
+		},
+		{
+			name:         "invalid-credentials",
+			err:          errors.New("error"),
+			status:       http.StatusUnauthorized,
+			unauthorized: 1,
+		},
+		{
+			name:         "read-only",
+			role:         auth.RoleReadOnly,
+			status:       http.StatusForbidden,
+			unauthorized: 1,
+		},
+		{
+			name:   "admin",
+			role:   auth.RoleAdmin,
+			status: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := mockConfig{
+				doSetErrorsPercentage: func(value float64) error {
+					return nil
+				},
+			}
+
+			var unauthorized mockCounter
+
+			handler := api.Handler{
+				Config:        config,
+				Authenticator: mockAuthenticator{role: test.role, err: test.err},
+				Unauthorized:  &unauthorized,
+			}
+
+			response := doSetErrorsPercentageRequest(&handler, strings.NewReader("12"))
+
+			checkStatusCode(t, response, test.status)
+			checkIntEqual(t, "unauthorized count", unauthorized.count, test.unauthorized)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/cardinality"
+	"github.com/gorilla/mux"
+)
+
+type Cardinality interface {
+	State(now time.Time) cardinality.State
+	Set(series int, churnRate float64, now time.Time) error
+}
+
+func (h *Handler) setupCardinalityHandlers(router *mux.Router) {
+	if h.Cardinality == nil {
+		return
+	}
+
+	sub := router.
+		Path("/-/cardinality").
+		Subrouter()
+
+	sub.
+		Methods(http.MethodGet).
+		HandlerFunc(h.handleGetCardinality)
+
+	sub.
+		Methods(http.MethodPut).
+		HandlerFunc(h.handleSetCardinality)
+}
+
+func (h *Handler) handleGetCardinality(w http.ResponseWriter, r *http.Request) {
+	type Data struct {
+		Label         string  `json:"label"`
+		Series        int     `json:"series"`
+		ChurnRate     float64 `json:"churnRate"`
+		ActiveSeries  int     `json:"activeSeries"`
+		ChurnedSeries int     `json:"churnedSeries"`
+	}
+
+	state := h.Cardinality.State(time.Now())
+
+	h.writeJSON(w, r, Data{
+		Label:         state.Label,
+		Series:        state.Series,
+		ChurnRate:     state.ChurnRate,
+		ActiveSeries:  state.ActiveSeries,
+		ChurnedSeries: state.ChurnedSeries,
+	})
+}
+
+// handleSetCardinality changes the number of series and the churn rate.
+// Values missing from the JSON body of the request are left unchanged.
+func (h *Handler) handleSetCardinality(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Series    *int     `json:"series"`
+		ChurnRate *float64 `json:"churnRate"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&body); err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse cardinality: %v", err)
+		return
+	}
+
+	now := time.Now()
+
+	state := h.Cardinality.State(now)
+
+	if body.Series != nil {
+		state.Series = *body.Series
+	}
+
+	if body.ChurnRate != nil {
+		state.ChurnRate = *body.ChurnRate
+	}
+
+	if err := h.Cardinality.Set(state.Series, state.ChurnRate, now); err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "set cardinality: %v", err)
+		return
+	}
+
+	fmt.Fprintln(w, "OK")
+}
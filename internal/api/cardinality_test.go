@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/cardinality"
+)
+
+func TestHandlerCardinality(t *testing.T) {
+	var simulator cardinality.Simulator
+
+	handler := api.Handler{Cardinality: &simulator}
+
+	response := doRequestWithBody(&handler, http.MethodPut, "/-/cardinality", strings.NewReader(`{"series": 50, "churnRate": 1.5}`))
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, "OK\n")
+
+	response = doRequestWithBody(&handler, http.MethodPut, "/-/cardinality", strings.NewReader(`{"series": 20}`))
+	checkStatusCode(t, response, http.StatusOK)
+
+	response = doRequest(&handler, http.MethodGet, "/-/cardinality")
+	checkStatusCode(t, response, http.StatusOK)
+
+	var data struct {
+		Label        string  `json:"label"`
+		Series       int     `json:"series"`
+		ChurnRate    float64 `json:"churnRate"`
+		ActiveSeries int     `json:"activeSeries"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&data); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if data.Label != "user_id" || data.Series != 20 || data.ChurnRate != 1.5 || data.ActiveSeries != 20 {
+		t.Fatalf("invalid response: %+v", data)
+	}
+}
+
+func TestHandlerCardinalityInvalid(t *testing.T) {
+	bodies := []string{
+		`{"series": -1}`,
+		`{"churnRate": -1}`,
+		`{"unknown": 1}`,
+		`not json`,
+	}
+
+	for _, body := range bodies {
+		handler := api.Handler{Cardinality: &cardinality.Simulator{}}
+
+		response := doRequestWithBody(&handler, http.MethodPut, "/-/cardinality", strings.NewReader(body))
+		checkStatusCode(t, response, http.StatusBadRequest)
+	}
+}
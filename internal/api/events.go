@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/events"
+	"github.com/gorilla/mux"
+)
+
+type EventSource interface {
+	Subscribe() *events.Subscription
+	Unsubscribe(s *events.Subscription)
+}
+
+// eventsHeartbeatInterval is how often a comment is sent to idle clients, to
+// prevent proxies from closing the connection.
+const eventsHeartbeatInterval = 15 * time.Second
+
+func (h *Handler) setupEventsHandler(router *mux.Router) {
+	if h.Events == nil {
+		return
+	}
+
+	router.
+		Methods(http.MethodGet).
+		Path("/-/events").
+		HandlerFunc(h.handleEvents)
+}
+
+// handleEvents streams events as Server-Sent Events. The optional "type" query
+// parameter is a comma-separated list of the event types to stream. When
+// events are dropped because the client is too slow, a "dropped" event
+// reports the total number of events dropped so far.
+func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.httpError(w, r, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	types := parseEventTypes(r.URL.Query().Get("type"))
+
+	subscription := h.Events.Subscribe()
+	defer h.Events.Unsubscribe(subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	var dropped uint64
+
+	for {
+		select {
+		case e := <-subscription.Events():
+			if types != nil && !types[e.Type] {
+				continue
+			}
+
+			if err := writeEvent(w, e.Type, e); err != nil {
+				return
+			}
+
+			if current := subscription.Dropped(); current != dropped {
+				dropped = current
+
+				if err := writeEvent(w, "dropped", map[string]uint64{"dropped": dropped}); err != nil {
+					return
+				}
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+func parseEventTypes(value string) map[string]bool {
+	if value == "" {
+		return nil
+	}
+
+	types := make(map[string]bool)
+
+	for _, t := range strings.Split(value, ",") {
+		types[strings.TrimSpace(t)] = true
+	}
+
+	return types
+}
+
+func writeEvent(w http.ResponseWriter, name string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, encoded)
+
+	return err
+}
@@ -0,0 +1,116 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/events"
+)
+
+func TestHandlerEvents(t *testing.T) {
+	var broker events.Broker
+
+	handler := api.Handler{
+		Events: &broker,
+	}
+
+	body := streamEvents(t, &handler, "/-/events?type=request", func() {
+		broker.Publish(events.Event{
+			Type:   events.TypeConfig,
+			Config: &events.Config{ErrorsPercentage: 50},
+		})
+		broker.Publish(events.Event{
+			Type:    events.TypeRequest,
+			Request: &events.Request{Duration: 3, Error: true},
+		})
+	})
+
+	if !strings.Contains(body, "event: request\n") {
+		t.Fatalf("request event not streamed:\n%s", body)
+	}
+
+	if !strings.Contains(body, `"duration":3,"error":true`) {
+		t.Fatalf("request data not streamed:\n%s", body)
+	}
+
+	if strings.Contains(body, "event: config\n") {
+		t.Fatalf("config event not filtered:\n%s", body)
+	}
+}
+
+func TestHandlerEventsDisabled(t *testing.T) {
+	handler := api.Handler{}
+
+	response := doRequest(&handler, http.MethodGet, "/-/events")
+
+	checkStatusCode(t, response, http.StatusNotFound)
+}
+
+// nonFlushingWriter hides the http.Flusher implemented by the wrapped writer.
+type nonFlushingWriter struct {
+	http.ResponseWriter
+}
+
+func TestHandlerEventsStreamingNotSupported(t *testing.T) {
+	handler := api.Handler{
+		Events:   &events.Broker{},
+		Requests: &mockRequestObserver{},
+	}
+
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(nonFlushingWriter{recorder}, httptest.NewRequest(http.MethodGet, "/-/events", nil))
+
+	checkStatusCode(t, recorder.Result(), http.StatusInternalServerError)
+}
+
+// streamEvents requests the events endpoint, calls publish once the client is
+// subscribed, and returns the body received up to the first request event.
+func streamEvents(t *testing.T, handler http.Handler, path string, publish func()) string {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("do request: %v", err)
+	}
+	defer response.Body.Close()
+
+	checkStatusCode(t, response, http.StatusOK)
+
+	publish()
+
+	// Stop reading if the expected event doesn't arrive in time.
+	timer := time.AfterFunc(5*time.Second, cancel)
+	defer timer.Stop()
+
+	var body strings.Builder
+
+	buffer := make([]byte, 1024)
+
+	for !strings.Contains(body.String(), "event: request\n") {
+		n, err := response.Body.Read(buffer)
+		body.Write(buffer[:n])
+
+		if err != nil {
+			break
+		}
+	}
+
+	return body.String()
+}
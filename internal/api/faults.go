@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/faults"
+	"github.com/gorilla/mux"
+)
+
+type Faults interface {
+	Wrap(next http.Handler) http.Handler
+	State(now time.Time) faults.State
+	Set(spec faults.Spec, now time.Time) error
+	Clear()
+}
+
+func (h *Handler) setupFaultsHandlers(router *mux.Router) {
+	if h.Faults == nil {
+		return
+	}
+
+	sub := router.
+		Path("/-/faults").
+		Subrouter()
+
+	sub.
+		Methods(http.MethodGet).
+		HandlerFunc(h.handleGetFaults)
+
+	sub.
+		Methods(http.MethodPut).
+		HandlerFunc(h.handleSetFaults)
+
+	sub.
+		Methods(http.MethodDelete).
+		HandlerFunc(h.handleClearFaults)
+}
+
+// faultsSpec is the JSON representation of faults.Spec. Durations are in the
+// format accepted by time.ParseDuration.
+type faultsSpec struct {
+	Latency             string  `json:"latency,omitempty"`
+	LatencyJitter       string  `json:"latencyJitter,omitempty"`
+	ErrorPercentage     float64 `json:"errorPercentage,omitempty"`
+	ErrorCode           int     `json:"errorCode,omitempty"`
+	ResetPercentage     float64 `json:"resetPercentage,omitempty"`
+	TruncatePercentage  float64 `json:"truncatePercentage,omitempty"`
+	MalformedPercentage float64 `json:"malformedPercentage,omitempty"`
+	UnavailablePeriod   string  `json:"unavailablePeriod,omitempty"`
+	UnavailableDuration string  `json:"unavailableDuration,omitempty"`
+	Delay               string  `json:"delay,omitempty"`
+	Duration            string  `json:"duration,omitempty"`
+}
+
+func (s faultsSpec) toSpec() (faults.Spec, error) {
+	spec := faults.Spec{
+		ErrorPercentage:     s.ErrorPercentage,
+		ErrorCode:           s.ErrorCode,
+		ResetPercentage:     s.ResetPercentage,
+		TruncatePercentage:  s.TruncatePercentage,
+		MalformedPercentage: s.MalformedPercentage,
+	}
+
+	durations := []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"latency", s.Latency, &spec.Latency},
+		{"latencyJitter", s.LatencyJitter, &spec.LatencyJitter},
+		{"unavailablePeriod", s.UnavailablePeriod, &spec.UnavailablePeriod},
+		{"unavailableDuration", s.UnavailableDuration, &spec.UnavailableDuration},
+		{"delay", s.Delay, &spec.Delay},
+		{"duration", s.Duration, &spec.Duration},
+	}
+
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+
+		value, err := time.ParseDuration(d.value)
+		if err != nil {
+			return faults.Spec{}, fmt.Errorf("invalid %s: %v", d.name, err)
+		}
+
+		*d.dst = value
+	}
+
+	return spec, nil
+}
+
+func newFaultsSpec(spec faults.Spec) faultsSpec {
+	formatDuration := func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return d.String()
+	}
+
+	return faultsSpec{
+		Latency:             formatDuration(spec.Latency),
+		LatencyJitter:       formatDuration(spec.LatencyJitter),
+		ErrorPercentage:     spec.ErrorPercentage,
+		ErrorCode:           spec.ErrorCode,
+		ResetPercentage:     spec.ResetPercentage,
+		TruncatePercentage:  spec.TruncatePercentage,
+		MalformedPercentage: spec.MalformedPercentage,
+		UnavailablePeriod:   formatDuration(spec.UnavailablePeriod),
+		UnavailableDuration: formatDuration(spec.UnavailableDuration),
+		Delay:               formatDuration(spec.Delay),
+		Duration:            formatDuration(spec.Duration),
+	}
+}
+
+func (h *Handler) handleGetFaults(w http.ResponseWriter, r *http.Request) {
+	type Data struct {
+		Faults *faultsSpec `json:"faults"`
+		Active bool        `json:"active"`
+		Start  *time.Time  `json:"start,omitempty"`
+		End    *time.Time  `json:"end,omitempty"`
+	}
+
+	state := h.Faults.State(time.Now())
+
+	data := Data{
+		Active: state.Active,
+	}
+
+	if state.Set {
+		spec := newFaultsSpec(state.Spec)
+		data.Faults = &spec
+		data.Start = &state.Start
+
+		if !state.End.IsZero() {
+			data.End = &state.End
+		}
+	}
+
+	h.writeJSON(w, r, data)
+}
+
+// handleSetFaults replaces the injected faults with the ones in the JSON body
+// of the request.
+func (h *Handler) handleSetFaults(w http.ResponseWriter, r *http.Request) {
+	var body faultsSpec
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&body); err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse faults: %v", err)
+		return
+	}
+
+	spec, err := body.toSpec()
+	if err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse faults: %v", err)
+		return
+	}
+
+	if err := h.Faults.Set(spec, time.Now()); err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "set faults: %v", err)
+		return
+	}
+
+	fmt.Fprintln(w, "OK")
+}
+
+func (h *Handler) handleClearFaults(w http.ResponseWriter, r *http.Request) {
+	h.Faults.Clear()
+	fmt.Fprintln(w, "OK")
+}
@@ -0,0 +1,63 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/faults"
+)
+
+func TestHandlerFaults(t *testing.T) {
+	var injector faults.Injector
+
+	handler := api.Handler{
+		Metrics: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		Faults:  &injector,
+	}
+
+	body := `{"errorPercentage": 100, "errorCode": 503, "latency": "1ms"}`
+
+	response := doRequestWithBody(&handler, http.MethodPut, "/-/faults", strings.NewReader(body))
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, "OK\n")
+
+	checkStatusCode(t, doRequest(&handler, http.MethodGet, "/metrics"), http.StatusServiceUnavailable)
+
+	response = doRequest(&handler, http.MethodGet, "/-/faults")
+	checkStatusCode(t, response, http.StatusOK)
+
+	var data struct {
+		Faults map[string]interface{} `json:"faults"`
+		Active bool                   `json:"active"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&data); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if !data.Active || data.Faults["latency"] != "1ms" || data.Faults["errorCode"] != 503.0 {
+		t.Fatalf("invalid response: %+v", data)
+	}
+
+	checkStatusCode(t, doRequest(&handler, http.MethodDelete, "/-/faults"), http.StatusOK)
+	checkStatusCode(t, doRequest(&handler, http.MethodGet, "/metrics"), http.StatusOK)
+}
+
+func TestHandlerFaultsInvalid(t *testing.T) {
+	bodies := []string{
+		`{"errorPercentage": 200}`,
+		`{"latency": "soon"}`,
+		`{"unknown": 1}`,
+		`not json`,
+	}
+
+	for _, body := range bodies {
+		handler := api.Handler{Faults: &faults.Injector{}}
+
+		response := doRequestWithBody(&handler, http.MethodPut, "/-/faults", strings.NewReader(body))
+		checkStatusCode(t, response, http.StatusBadRequest)
+	}
+}
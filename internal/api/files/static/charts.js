@@ -0,0 +1,147 @@
+// A minimal line chart drawn on a canvas, so that the web interface doesn't
+// depend on external libraries or CDNs.
+(function (global) {
+    "use strict";
+
+    const padding = {top: 10, right: 10, bottom: 24, left: 56};
+    const gridLines = 4;
+
+    function formatTime(date) {
+        return date.toLocaleTimeString([], {hour: "2-digit", minute: "2-digit", second: "2-digit"});
+    }
+
+    function defaultFormat(value) {
+        if (value === 0) {
+            return "0";
+        }
+        if (Math.abs(value) >= 100) {
+            return value.toFixed(0);
+        }
+        if (Math.abs(value) >= 1) {
+            return value.toFixed(2).replace(/\.?0+$/, "");
+        }
+        return value.toPrecision(2);
+    }
+
+    // Draw a line chart.
+    //
+    // options.times is an array of Date objects for the x axis.
+    // options.series is an array of {name, color, values} objects, where values
+    // has the same length as times.
+    // options.format, if set, formats the values on the y axis.
+    // options.min and options.max, if set, fix the range of the y axis.
+    function line(canvas, options) {
+        const ratio = global.devicePixelRatio || 1;
+        const width = canvas.clientWidth;
+        const height = canvas.clientHeight;
+
+        canvas.width = width * ratio;
+        canvas.height = height * ratio;
+
+        const ctx = canvas.getContext("2d");
+        ctx.setTransform(ratio, 0, 0, ratio, 0, 0);
+        ctx.clearRect(0, 0, width, height);
+        ctx.font = "11px sans-serif";
+
+        const format = options.format || defaultFormat;
+        const times = options.times;
+        const series = options.series;
+
+        let min = options.min;
+        let max = options.max;
+
+        series.forEach(function (s) {
+            s.values.forEach(function (v) {
+                if (options.min === undefined && (min === undefined || v < min)) {
+                    min = v;
+                }
+                if (options.max === undefined && (max === undefined || v > max)) {
+                    max = v;
+                }
+            });
+        });
+
+        if (min === undefined || max === undefined) {
+            min = 0;
+            max = 1;
+        }
+        if (min > 0 && options.min === undefined) {
+            min = 0;
+        }
+        if (max === min) {
+            max = min + 1;
+        }
+
+        const plotWidth = width - padding.left - padding.right;
+        const plotHeight = height - padding.top - padding.bottom;
+
+        function x(i) {
+            if (times.length < 2) {
+                return padding.left + plotWidth;
+            }
+            return padding.left + (i / (times.length - 1)) * plotWidth;
+        }
+
+        function y(v) {
+            return padding.top + plotHeight - ((v - min) / (max - min)) * plotHeight;
+        }
+
+        // Grid and y axis labels.
+        ctx.strokeStyle = "#e0e0e0";
+        ctx.fillStyle = "#555";
+        ctx.textAlign = "right";
+        ctx.textBaseline = "middle";
+
+        for (let i = 0; i <= gridLines; i++) {
+            const value = min + (i / gridLines) * (max - min);
+            const py = Math.round(y(value)) + 0.5;
+
+            ctx.beginPath();
+            ctx.moveTo(padding.left, py);
+            ctx.lineTo(padding.left + plotWidth, py);
+            ctx.stroke();
+            ctx.fillText(format(value), padding.left - 6, py);
+        }
+
+        // X axis labels.
+        if (times.length > 0) {
+            ctx.textBaseline = "top";
+            ctx.textAlign = "left";
+            ctx.fillText(formatTime(times[0]), padding.left, padding.top + plotHeight + 6);
+            ctx.textAlign = "right";
+            ctx.fillText(formatTime(times[times.length - 1]), padding.left + plotWidth, padding.top + plotHeight + 6);
+        }
+
+        // Lines.
+        ctx.lineWidth = 1.5;
+
+        series.forEach(function (s) {
+            ctx.strokeStyle = s.color;
+            ctx.beginPath();
+            s.values.forEach(function (v, i) {
+                if (i === 0) {
+                    ctx.moveTo(x(i), y(v));
+                } else {
+                    ctx.lineTo(x(i), y(v));
+                }
+            });
+            ctx.stroke();
+        });
+
+        // Legend.
+        let legendX = padding.left + 8;
+
+        ctx.textAlign = "left";
+        ctx.textBaseline = "middle";
+
+        series.forEach(function (s) {
+            ctx.fillStyle = s.color;
+            ctx.fillRect(legendX, padding.top + 4, 10, 3);
+            ctx.fillStyle = "#333";
+            ctx.fillText(s.name, legendX + 14, padding.top + 6);
+            legendX += 24 + ctx.measureText(s.name).width;
+        });
+    }
+
+    global.Charts = {line: line};
+})(window);
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/gaps"
+	"github.com/gorilla/mux"
+)
+
+type Gaps interface {
+	Hide(metric string, labels map[string]string, until time.Time) (gaps.Rule, error)
+	Show(id string) bool
+	Pause(until time.Time)
+	Resume()
+	State(now time.Time) gaps.State
+}
+
+func (h *Handler) setupGapsHandlers(router *mux.Router) {
+	if h.Gaps == nil {
+		return
+	}
+
+	router.
+		Methods(http.MethodGet).
+		Path("/-/gaps").
+		HandlerFunc(h.handleGetGaps)
+
+	router.
+		Methods(http.MethodPost).
+		Path("/-/gaps/series").
+		HandlerFunc(h.handleHideSeries)
+
+	router.
+		Methods(http.MethodDelete).
+		Path("/-/gaps/series/{id}").
+		HandlerFunc(h.handleShowSeries)
+
+	pause := router.
+		Path("/-/gaps/pause").
+		Subrouter()
+
+	pause.
+		Methods(http.MethodPut).
+		HandlerFunc(h.handlePause)
+
+	pause.
+		Methods(http.MethodDelete).
+		HandlerFunc(h.handleResume)
+}
+
+type hiddenSeries struct {
+	ID     string            `json:"id"`
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Until  *time.Time        `json:"until,omitempty"`
+}
+
+func newHiddenSeries(rule gaps.Rule) hiddenSeries {
+	data := hiddenSeries{
+		ID:     rule.ID,
+		Metric: rule.Metric,
+		Labels: rule.Labels,
+	}
+
+	if !rule.Until.IsZero() {
+		data.Until = &rule.Until
+	}
+
+	return data
+}
+
+func (h *Handler) handleGetGaps(w http.ResponseWriter, r *http.Request) {
+	type Data struct {
+		Paused      bool           `json:"paused"`
+		PausedUntil *time.Time     `json:"pausedUntil,omitempty"`
+		Hidden      []hiddenSeries `json:"hidden"`
+	}
+
+	state := h.Gaps.State(time.Now())
+
+	data := Data{
+		Paused: state.Paused,
+		Hidden: []hiddenSeries{},
+	}
+
+	if !state.PausedUntil.IsZero() {
+		data.PausedUntil = &state.PausedUntil
+	}
+
+	for _, rule := range state.Hidden {
+		data.Hidden = append(data.Hidden, newHiddenSeries(rule))
+	}
+
+	h.writeJSON(w, r, data)
+}
+
+// handleHideSeries hides from the metrics endpoint the series of a metric
+// matching the labels in the JSON body of the request. If the body contains a
+// duration, the series are hidden only for that long.
+func (h *Handler) handleHideSeries(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Metric   string            `json:"metric"`
+		Labels   map[string]string `json:"labels"`
+		Duration string            `json:"duration"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&body); err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse series: %v", err)
+		return
+	}
+
+	var until time.Time
+
+	if body.Duration != "" {
+		duration, err := time.ParseDuration(body.Duration)
+		if err != nil || duration <= 0 {
+			h.httpError(w, r, http.StatusBadRequest, "invalid duration %q", body.Duration)
+			return
+		}
+
+		until = time.Now().Add(duration)
+	}
+
+	rule, err := h.Gaps.Hide(body.Metric, body.Labels, until)
+	if err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "hide series: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/-/gaps/series/"+rule.ID)
+	w.WriteHeader(http.StatusCreated)
+
+	h.writeJSON(w, r, newHiddenSeries(rule))
+}
+
+func (h *Handler) handleShowSeries(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if !h.Gaps.Show(id) {
+		h.httpError(w, r, http.StatusNotFound, "hidden series %q not found", id)
+		return
+	}
+
+	fmt.Fprintln(w, "OK")
+}
+
+// handlePause pauses the observation of simulated requests until it is
+// resumed or, if the "duration" query parameter is set, for that long.
+func (h *Handler) handlePause(w http.ResponseWriter, r *http.Request) {
+	var until time.Time
+
+	if value := r.URL.Query().Get("duration"); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			h.httpError(w, r, http.StatusBadRequest, "invalid duration %q", value)
+			return
+		}
+
+		until = time.Now().Add(duration)
+	}
+
+	h.Gaps.Pause(until)
+
+	fmt.Fprintln(w, "OK")
+}
+
+func (h *Handler) handleResume(w http.ResponseWriter, r *http.Request) {
+	h.Gaps.Resume()
+	fmt.Fprintln(w, "OK")
+}
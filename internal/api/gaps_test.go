@@ -0,0 +1,74 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/gaps"
+)
+
+func TestHandlerGaps(t *testing.T) {
+	var g gaps.Gaps
+
+	handler := api.Handler{Gaps: &g}
+
+	body := `{"metric": "metrics_generator_request_errors_count", "labels": {"code": "500"}, "duration": "5m"}`
+
+	response := doRequestWithBody(&handler, http.MethodPost, "/-/gaps/series", strings.NewReader(body))
+	checkStatusCode(t, response, http.StatusCreated)
+
+	var rule struct {
+		ID string `json:"id"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&rule); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	checkStatusCode(t, doRequest(&handler, http.MethodPut, "/-/gaps/pause?duration=1m"), http.StatusOK)
+
+	response = doRequest(&handler, http.MethodGet, "/-/gaps")
+	checkStatusCode(t, response, http.StatusOK)
+
+	var data struct {
+		Paused      bool                     `json:"paused"`
+		PausedUntil string                   `json:"pausedUntil"`
+		Hidden      []map[string]interface{} `json:"hidden"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&data); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if !data.Paused || data.PausedUntil == "" || len(data.Hidden) != 1 || data.Hidden[0]["id"] != rule.ID {
+		t.Fatalf("invalid response: %+v", data)
+	}
+
+	checkStatusCode(t, doRequest(&handler, http.MethodDelete, "/-/gaps/pause"), http.StatusOK)
+	checkStatusCode(t, doRequest(&handler, http.MethodDelete, "/-/gaps/series/"+rule.ID), http.StatusOK)
+	checkStatusCode(t, doRequest(&handler, http.MethodDelete, "/-/gaps/series/"+rule.ID), http.StatusNotFound)
+
+	response = doRequest(&handler, http.MethodGet, "/-/gaps")
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, "{\"paused\":false,\"hidden\":[]}\n")
+}
+
+func TestHandlerGapsInvalid(t *testing.T) {
+	handler := api.Handler{Gaps: &gaps.Gaps{}}
+
+	bodies := []string{
+		`{"metric": ""}`,
+		`{"metric": "up", "duration": "soon"}`,
+		`{"metric": "up", "unknown": 1}`,
+	}
+
+	for _, body := range bodies {
+		response := doRequestWithBody(&handler, http.MethodPost, "/-/gaps/series", strings.NewReader(body))
+		checkStatusCode(t, response, http.StatusBadRequest)
+	}
+
+	checkStatusCode(t, doRequest(&handler, http.MethodPut, "/-/gaps/pause?duration=-1m"), http.StatusBadRequest)
+}
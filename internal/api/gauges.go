@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/gauges"
+	"github.com/gorilla/mux"
+)
+
+type Gauges interface {
+	Put(g gauges.Gauge) error
+	Delete(name string) bool
+	List(now time.Time) []gauges.Value
+}
+
+func (h *Handler) setupGaugesHandlers(router *mux.Router) {
+	if h.Gauges == nil {
+		return
+	}
+
+	router.
+		Methods(http.MethodGet).
+		Path("/-/gauges").
+		HandlerFunc(h.handleListGauges)
+
+	sub := router.
+		Path("/-/gauges/{name}").
+		Subrouter()
+
+	sub.
+		Methods(http.MethodPut).
+		HandlerFunc(h.handlePutGauge)
+
+	sub.
+		Methods(http.MethodDelete).
+		HandlerFunc(h.handleDeleteGauge)
+}
+
+// gaugeModel is the JSON representation of gauges.Model. Durations are in the
+// format accepted by time.ParseDuration.
+type gaugeModel struct {
+	Type       string  `json:"type"`
+	Min        float64 `json:"min,omitempty"`
+	Max        float64 `json:"max,omitempty"`
+	Period     string  `json:"period,omitempty"`
+	Volatility float64 `json:"volatility,omitempty"`
+	Mean       float64 `json:"mean,omitempty"`
+	Reversion  float64 `json:"reversion,omitempty"`
+	Window     string  `json:"window,omitempty"`
+}
+
+func (m gaugeModel) toModel() (gauges.Model, error) {
+	model := gauges.Model{
+		Type:       m.Type,
+		Min:        m.Min,
+		Max:        m.Max,
+		Volatility: m.Volatility,
+		Mean:       m.Mean,
+		Reversion:  m.Reversion,
+	}
+
+	durations := []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"period", m.Period, &model.Period},
+		{"window", m.Window, &model.Window},
+	}
+
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+
+		value, err := time.ParseDuration(d.value)
+		if err != nil {
+			return gauges.Model{}, fmt.Errorf("invalid %s: %v", d.name, err)
+		}
+
+		*d.dst = value
+	}
+
+	return model, nil
+}
+
+func newGaugeModel(model gauges.Model) gaugeModel {
+	formatDuration := func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return d.String()
+	}
+
+	return gaugeModel{
+		Type:       model.Type,
+		Min:        model.Min,
+		Max:        model.Max,
+		Period:     formatDuration(model.Period),
+		Volatility: model.Volatility,
+		Mean:       model.Mean,
+		Reversion:  model.Reversion,
+		Window:     formatDuration(model.Window),
+	}
+}
+
+func (h *Handler) handleListGauges(w http.ResponseWriter, r *http.Request) {
+	type Data struct {
+		Name   string     `json:"name"`
+		Metric string     `json:"metric"`
+		Help   string     `json:"help"`
+		Model  gaugeModel `json:"model"`
+		Value  float64    `json:"value"`
+	}
+
+	data := []Data{}
+
+	for _, v := range h.Gauges.List(time.Now()) {
+		data = append(data, Data{
+			Name:   v.Name,
+			Metric: gauges.MetricPrefix + v.Name,
+			Help:   v.Help,
+			Model:  newGaugeModel(v.Model),
+			Value:  v.Value,
+		})
+	}
+
+	h.writeJSON(w, r, data)
+}
+
+// handlePutGauge adds a gauge with the help and the model in the JSON body of
+// the request, or replaces the gauge with the same name.
+func (h *Handler) handlePutGauge(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Help  string     `json:"help"`
+		Model gaugeModel `json:"model"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&body); err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse gauge: %v", err)
+		return
+	}
+
+	model, err := body.Model.toModel()
+	if err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse gauge: %v", err)
+		return
+	}
+
+	gauge := gauges.Gauge{
+		Name:  mux.Vars(r)["name"],
+		Help:  body.Help,
+		Model: model,
+	}
+
+	if err := h.Gauges.Put(gauge); err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "set gauge: %v", err)
+		return
+	}
+
+	fmt.Fprintln(w, "OK")
+}
+
+func (h *Handler) handleDeleteGauge(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if !h.Gauges.Delete(name) {
+		h.httpError(w, r, http.StatusNotFound, "gauge %q not found", name)
+		return
+	}
+
+	fmt.Fprintln(w, "OK")
+}
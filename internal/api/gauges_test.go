@@ -0,0 +1,63 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/gauges"
+)
+
+func TestHandlerGauges(t *testing.T) {
+	var set gauges.Set
+
+	handler := api.Handler{Gauges: &set}
+
+	body := `{"help": "Queued jobs", "model": {"type": "sawtooth", "min": 0, "max": 10, "period": "1m"}}`
+
+	response := doRequestWithBody(&handler, http.MethodPut, "/-/gauges/queue_depth", strings.NewReader(body))
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, "OK\n")
+
+	response = doRequest(&handler, http.MethodGet, "/-/gauges")
+	checkStatusCode(t, response, http.StatusOK)
+
+	var data []struct {
+		Name   string `json:"name"`
+		Metric string `json:"metric"`
+		Help   string `json:"help"`
+		Model  struct {
+			Type   string `json:"type"`
+			Period string `json:"period"`
+		} `json:"model"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&data); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if len(data) != 1 || data[0].Metric != "metrics_generator_gauge_queue_depth" || data[0].Help != "Queued jobs" || data[0].Model.Period != "1m0s" {
+		t.Fatalf("invalid response: %+v", data)
+	}
+
+	checkStatusCode(t, doRequest(&handler, http.MethodDelete, "/-/gauges/queue_depth"), http.StatusOK)
+	checkStatusCode(t, doRequest(&handler, http.MethodDelete, "/-/gauges/queue_depth"), http.StatusNotFound)
+}
+
+func TestHandlerGaugesInvalid(t *testing.T) {
+	handler := api.Handler{Gauges: &gauges.Set{}}
+
+	bodies := []string{
+		`{"model": {"type": "unknown"}}`,
+		`{"model": {"type": "sine", "min": 0, "max": 1, "period": "soon"}}`,
+		`{"model": {"type": "traffic"}, "unknown": 1}`,
+		`not json`,
+	}
+
+	for _, body := range bodies {
+		response := doRequestWithBody(&handler, http.MethodPut, "/-/gauges/test", strings.NewReader(body))
+		checkStatusCode(t, response, http.StatusBadRequest)
+	}
+}
@@ -1,14 +1,20 @@
 package api
 
 import (
-	_ "embed"
+	"embed"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
+	"github.com/francescomari/metrics-generator/internal/auth"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/gorilla/mux"
 )
 
@@ -21,9 +27,87 @@ type Config interface {
 	SetRequestsHour(reqHour int) error
 }
 
+type Reloader interface {
+	Reload() error
+}
+
+type Restarter interface {
+	Restart(now time.Time)
+}
+
+type Authenticator interface {
+	Authenticate(r *http.Request) (auth.Role, error)
+}
+
+type Counter interface {
+	Inc()
+}
+
 type Handler struct {
-	Config  Config
-	Metrics http.Handler
+	Config   Config
+	Metrics  http.Handler
+	Reloader Reloader
+
+	// Events, if set, is streamed to clients of the events endpoint.
+	Events EventSource
+
+	// Stats, if set, provides the statistics about the generated data.
+	Stats Stats
+
+	// StatsWindows are the windows reported by the statistics endpoint when
+	// the request doesn't specify them. If empty, DefaultStatsWindows is
+	// used.
+	StatsWindows []time.Duration
+
+	// Authenticator, if set, protects the endpoints that change the state of
+	// the generator. Requests using any method other than GET and HEAD are
+	// only allowed for clients with the admin role.
+	Authenticator Authenticator
+
+	// Unauthorized, if set, counts the requests rejected by the
+	// Authenticator.
+	Unauthorized Counter
+
+	// Probes, if set, backs the readiness and liveness probes.
+	Probes Probes
+
+	// Faults, if set, injects faults in the responses of the metrics
+	// endpoint.
+	Faults Faults
+
+	// Anomalies, if set, manages the anomalies applied to the simulated
+	// requests.
+	Anomalies Anomalies
+
+	// Cardinality, if set, controls the series created to simulate a
+	// cardinality explosion.
+	Cardinality Cardinality
+
+	// Gaps, if set, hides series from the metrics endpoint and pauses the
+	// observation of simulated requests.
+	Gaps Gaps
+
+	// Gauges, if set, manages the synthetic gauges.
+	Gauges Gauges
+
+	// Restarter, if set, simulates restarts of the process exposing the
+	// synthetic metrics.
+	Restarter Restarter
+
+	// SelfMetrics, if set, serves the metrics about the generator itself on
+	// a separate endpoint.
+	SelfMetrics http.Handler
+
+	// Requests, if set, observes every request served by the handler.
+	Requests RequestObserver
+
+	// Logger, if set, receives the errors returned to clients and the
+	// rejected requests.
+	Logger log.Logger
+
+	// MetricsOnly restricts the handler to the metrics, health and probe
+	// endpoints.
+	MetricsOnly bool
 
 	once    sync.Once
 	handler http.Handler
@@ -37,12 +121,38 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) setupHandlers() {
 	router := mux.NewRouter()
 
+	if h.Requests != nil {
+		router.Use(h.instrument)
+	}
+
+	if h.Authenticator != nil {
+		router.Use(h.requireAdminForChanges)
+	}
+
 	h.setupHealthHandler(router)
-	h.setupDurationIntervalHandlers(router)
-	h.setupErrorsPercentageHandlers(router)
-	h.setupRequestsHourHandlers(router)
+	h.setupProbeHandlers(router)
 	h.setupMetricsHandler(router)
-	h.setupRootHandler(router)
+	h.setupSelfMetricsHandler(router)
+
+	if !h.MetricsOnly {
+		h.setupConfigHandler(router)
+		h.setupDurationIntervalHandlers(router)
+		h.setupErrorsPercentageHandlers(router)
+		h.setupRequestsHourHandlers(router)
+		h.setupReloadHandler(router)
+		h.setupRestartHandler(router)
+		h.setupProbesAdminHandlers(router)
+		h.setupFaultsHandlers(router)
+		h.setupAnomaliesHandlers(router)
+		h.setupCardinalityHandlers(router)
+		h.setupGapsHandlers(router)
+		h.setupGaugesHandlers(router)
+		h.setupEventsHandler(router)
+		h.setupStatsHandlers(router)
+		h.setupStaticHandler(router)
+		h.setupOpenAPIHandler(router)
+		h.setupRootHandler(router)
+	}
 
 	h.handler = router
 }
@@ -61,6 +171,13 @@ func (h *Handler) setupHealthHandler(router *mux.Router) {
 		HandlerFunc(h.handleHealth)
 }
 
+func (h *Handler) setupConfigHandler(router *mux.Router) {
+	router.
+		Methods(http.MethodGet).
+		Path("/-/config").
+		HandlerFunc(h.handleGetConfig)
+}
+
 func (h *Handler) setupDurationIntervalHandlers(router *mux.Router) {
 	sub := router.
 		PathPrefix("/-/config/duration-interval").
@@ -103,16 +220,98 @@ func (h *Handler) setupRequestsHourHandlers(router *mux.Router) {
 		HandlerFunc(h.handleSetRequestsHour)
 }
 
+func (h *Handler) setupReloadHandler(router *mux.Router) {
+	if h.Reloader == nil {
+		return
+	}
+
+	router.
+		Methods(http.MethodPost, http.MethodPut).
+		Path("/-/reload").
+		HandlerFunc(h.handleReload)
+}
+
+func (h *Handler) setupRestartHandler(router *mux.Router) {
+	if h.Restarter == nil {
+		return
+	}
+
+	router.
+		Methods(http.MethodPost).
+		Path("/-/restart").
+		HandlerFunc(h.handleRestart)
+}
+
 func (h *Handler) setupMetricsHandler(router *mux.Router) {
+	if h.Metrics == nil {
+		return
+	}
+
+	handler := h.Metrics
+
+	if h.Faults != nil {
+		handler = h.Faults.Wrap(handler)
+	}
+
 	router.
 		Methods(http.MethodGet).
 		Path("/metrics").
-		Handler(h.Metrics)
+		Handler(handler)
+}
+
+func (h *Handler) setupSelfMetricsHandler(router *mux.Router) {
+	if h.SelfMetrics == nil {
+		return
+	}
+
+	router.
+		Methods(http.MethodGet).
+		Path("/-/metrics").
+		Handler(h.SelfMetrics)
 }
 
 //go:embed files/index.html
 var index string
 
+//go:embed files/static
+var embeddedStatic embed.FS
+
+// static contains the files served under /-/static/.
+var static = mustSub(embeddedStatic, "files/static")
+
+// mustSub returns the subtree of fsys rooted at dir. It is only used with
+// embedded files, whose layout is fixed at build time, so an error is a bug.
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
+
+//go:embed files/openapi.json
+var openAPI []byte
+
+func (h *Handler) setupOpenAPIHandler(router *mux.Router) {
+	router.
+		Methods(http.MethodGet).
+		Path("/-/openapi.json").
+		HandlerFunc(h.handleOpenAPI)
+}
+
+func (h *Handler) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPI)
+}
+
+func (h *Handler) setupStaticHandler(router *mux.Router) {
+	router.
+		Methods(http.MethodGet).
+		PathPrefix("/-/static/").
+		Handler(http.StripPrefix("/-/static/", http.FileServer(http.FS(static))))
+}
+
 func (h *Handler) handleRoot(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", " text/html")
 
@@ -134,12 +333,12 @@ func (h *Handler) handleRoot(w http.ResponseWriter, r *http.Request) {
 
 	tmpl, err := template.New("index").Parse(index)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, "generating template: %v", err)
+		h.httpError(w, r, http.StatusInternalServerError, "generating template: %v", err)
 		return
 	}
 	err = tmpl.Execute(w, data)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, "executing template: %v", err)
+		h.httpError(w, r, http.StatusInternalServerError, "executing template: %v", err)
 		return
 	}
 }
@@ -148,6 +347,32 @@ func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "OK")
 }
 
+func (h *Handler) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	type DurationInterval struct {
+		Min int `json:"min"`
+		Max int `json:"max"`
+	}
+
+	type Data struct {
+		DurationInterval DurationInterval `json:"durationInterval"`
+		ErrorsPercentage float64          `json:"errorsPercentage"`
+		RequestsHour     int              `json:"requestsHour"`
+	}
+
+	min, max := h.Config.DurationInterval()
+
+	data := Data{
+		DurationInterval: DurationInterval{
+			Min: min,
+			Max: max,
+		},
+		ErrorsPercentage: h.Config.ErrorsPercentage(),
+		RequestsHour:     h.Config.RequestsHour(),
+	}
+
+	h.writeJSON(w, r, data)
+}
+
 func (h *Handler) handleGetDurationInterval(w http.ResponseWriter, r *http.Request) {
 	min, max := h.Config.DurationInterval()
 	fmt.Fprintf(w, "%d,%d\n", min, max)
@@ -156,18 +381,18 @@ func (h *Handler) handleGetDurationInterval(w http.ResponseWriter, r *http.Reque
 func (h *Handler) handleSetDurationInterval(w http.ResponseWriter, r *http.Request) {
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, "read body: %v", err)
+		h.httpError(w, r, http.StatusInternalServerError, "read body: %v", err)
 		return
 	}
 
 	min, max, err := parseDurationInterval(string(data))
 	if err != nil {
-		httpError(w, http.StatusBadRequest, "parse duration interval: %v", err)
+		h.httpError(w, r, http.StatusBadRequest, "parse duration interval: %v", err)
 		return
 	}
 
 	if err := h.Config.SetDurationInterval(min, max); err != nil {
-		httpError(w, http.StatusBadRequest, "set duration interval: %v", err)
+		h.httpError(w, r, http.StatusBadRequest, "set duration interval: %v", err)
 		return
 	}
 
@@ -182,26 +407,70 @@ func (h *Handler) handleGetErrorsPercentage(w http.ResponseWriter, r *http.Reque
 func (h *Handler) handleSetErrorsPercentage(w http.ResponseWriter, r *http.Request) {
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, "read body: %v", err)
+		h.httpError(w, r, http.StatusInternalServerError, "read body: %v", err)
 		return
 	}
 
 	value, err := strconv.ParseFloat(string(data), 64)
 	if err != nil {
-		httpError(w, http.StatusBadRequest, "parse errors percentage: %v", err)
+		h.httpError(w, r, http.StatusBadRequest, "parse errors percentage: %v", err)
 		return
 	}
 
 	if err := h.Config.SetErrorsPercentage(value); err != nil {
-		httpError(w, http.StatusBadRequest, "set errors percentage: %v", err)
+		h.httpError(w, r, http.StatusBadRequest, "set errors percentage: %v", err)
+		return
+	}
+
+	fmt.Fprintln(w, "OK")
+}
+
+func (h *Handler) handleReload(w http.ResponseWriter, r *http.Request) {
+	if err := h.Reloader.Reload(); err != nil {
+		h.httpError(w, r, http.StatusInternalServerError, "reload: %v", err)
 		return
 	}
 
 	fmt.Fprintln(w, "OK")
 }
 
-func httpError(w http.ResponseWriter, code int, format string, args ...interface{}) {
-	http.Error(w, fmt.Sprintf(format, args...), code)
+// handleRestart resets the synthetic metrics, as if the process exposing
+// them was restarted.
+func (h *Handler) handleRestart(w http.ResponseWriter, r *http.Request) {
+	h.Restarter.Restart(time.Now())
+	fmt.Fprintln(w, "OK")
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, r *http.Request, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.httpError(w, r, http.StatusInternalServerError, "encode JSON: %v", err)
+	}
+}
+
+// httpError responds with an error and logs it. Client errors are logged as
+// warnings, server errors as errors.
+func (h *Handler) httpError(w http.ResponseWriter, r *http.Request, code int, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+
+	logger := level.Warn(h.logger())
+
+	if code >= http.StatusInternalServerError {
+		logger = level.Error(h.logger())
+	}
+
+	logger.Log("msg", "API request failed", "method", r.Method, "path", r.URL.Path, "code", code, "err", message)
+
+	http.Error(w, message, code)
+}
+
+func (h *Handler) logger() log.Logger {
+	if h.Logger == nil {
+		return log.NewNopLogger()
+	}
+
+	return h.Logger
 }
 
 func (h *Handler) handleGetRequestsHour(w http.ResponseWriter, r *http.Request) {
@@ -211,18 +480,18 @@ func (h *Handler) handleGetRequestsHour(w http.ResponseWriter, r *http.Request)
 func (h *Handler) handleSetRequestsHour(w http.ResponseWriter, r *http.Request) {
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, "read body: %v", err)
+		h.httpError(w, r, http.StatusInternalServerError, "read body: %v", err)
 		return
 	}
 
 	value, err := strconv.Atoi(string(data))
 	if err != nil {
-		httpError(w, http.StatusBadRequest, "parse requests hour: %v", err)
+		h.httpError(w, r, http.StatusBadRequest, "parse requests hour: %v", err)
 		return
 	}
 
 	if err := h.Config.SetRequestsHour(value); err != nil {
-		httpError(w, http.StatusBadRequest, "set requests hour: %v", err)
+		h.httpError(w, r, http.StatusBadRequest, "set requests hour: %v", err)
 		return
 	}
 
@@ -10,7 +10,10 @@ import (
 	"testing/iotest"
 
 	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/restart"
 	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 type mockConfig struct {
@@ -44,6 +47,12 @@ func (c mockConfig) SetRequestsHour(value int) error {
 	return c.doSetReqHours(value)
 }
 
+type mockReloader func() error
+
+func (r mockReloader) Reload() error {
+	return r()
+}
+
 func TestHandlerRoot(t *testing.T) {
 	config := mockConfig{
 		doDurationInterval: func() (int, int) {
@@ -90,6 +99,65 @@ func TestHandlerHealth(t *testing.T) {
 	checkBody(t, response, "OK\n")
 }
 
+func TestHandlerMetrics(t *testing.T) {
+	handler := api.Handler{
+		Metrics: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "metrics")
+		}),
+	}
+
+	response := doMetricsRequest(&handler)
+
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, "metrics")
+}
+
+func TestHandlerMetricsDisabled(t *testing.T) {
+	handler := api.Handler{}
+
+	response := doMetricsRequest(&handler)
+
+	checkStatusCode(t, response, http.StatusNotFound)
+}
+
+func TestHandlerMetricsOnly(t *testing.T) {
+	handler := api.Handler{
+		Config: mockConfig{
+			doErrorsPercentage: func() float64 {
+				return 12
+			},
+		},
+		Metrics: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "metrics")
+		}),
+		MetricsOnly: true,
+	}
+
+	checkStatusCode(t, doMetricsRequest(&handler), http.StatusOK)
+	checkStatusCode(t, doHealthRequest(&handler), http.StatusOK)
+	checkStatusCode(t, doGetErrorsPercentageRequest(&handler), http.StatusNotFound)
+	checkStatusCode(t, doIndexRequest(&handler), http.StatusNotFound)
+}
+
+func TestHandlerGetConfig(t *testing.T) {
+	config := mockConfig{
+		doDurationInterval: func() (int, int) {
+			return 12, 34
+		},
+		doErrorsPercentage: func() float64 {
+			return 12.5
+		},
+		doReqHours: func() int {
+			return 100
+		},
+	}
+
+	response := doGetConfigRequest(handlerForConfig(config))
+
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, `{"durationInterval":{"min":12,"max":34},"errorsPercentage":12.5,"requestsHour":100}`+"\n")
+}
+
 func TestHandlerGetDurationInterval(t *testing.T) {
 	config := mockConfig{
 		doDurationInterval: func() (int, int) {
@@ -251,6 +319,76 @@ func TestHandlerSetReqHourReadError(t *testing.T) {
 	checkStatusCode(t, response, http.StatusBadRequest)
 }
 
+func TestHandlerReload(t *testing.T) {
+	var reloaded bool
+
+	handler := api.Handler{
+		Reloader: mockReloader(func() error {
+			reloaded = true
+			return nil
+		}),
+	}
+
+	response := doReloadRequest(&handler)
+
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, "OK\n")
+
+	if !reloaded {
+		t.Fatalf("configuration not reloaded")
+	}
+}
+
+func TestHandlerReloadError(t *testing.T) {
+	handler := api.Handler{
+		Reloader: mockReloader(func() error {
+			return errors.New("error")
+		}),
+	}
+
+	response := doReloadRequest(&handler)
+
+	checkStatusCode(t, response, http.StatusInternalServerError)
+}
+
+func TestHandlerReloadDisabled(t *testing.T) {
+	handler := api.Handler{}
+
+	response := doReloadRequest(&handler)
+
+	checkStatusCode(t, response, http.StatusNotFound)
+}
+
+func TestHandlerRestart(t *testing.T) {
+	var process restart.Process
+
+	counter := process.NewCounter(prometheus.CounterOpts{Name: "test_total"})
+	counter.Inc()
+
+	handler := api.Handler{Restarter: &process}
+
+	response := doRequest(&handler, http.MethodPost, "/-/restart")
+
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, "OK\n")
+
+	if value := testutil.ToFloat64(counter); value != 0 {
+		t.Fatalf("counter not reset: %v", value)
+	}
+}
+
+func TestHandlerRestartDisabled(t *testing.T) {
+	handler := api.Handler{}
+
+	response := doRequest(&handler, http.MethodPost, "/-/restart")
+
+	checkStatusCode(t, response, http.StatusNotFound)
+}
+
+func doGetConfigRequest(handler http.Handler) *http.Response {
+	return doRequest(handler, http.MethodGet, "/-/config")
+}
+
 func doGetDurationIntervalRequest(handler http.Handler) *http.Response {
 	return doRequest(handler, http.MethodGet, "/-/config/duration-interval")
 }
@@ -275,10 +413,18 @@ func doSetReqHour(handler http.Handler, body io.Reader) *http.Response {
 	return doRequestWithBody(handler, http.MethodPut, "/-/config/requests-hour", body)
 }
 
+func doReloadRequest(handler http.Handler) *http.Response {
+	return doRequest(handler, http.MethodPost, "/-/reload")
+}
+
 func doIndexRequest(handler http.Handler) *http.Response {
 	return doRequest(handler, http.MethodGet, "/")
 }
 
+func doMetricsRequest(handler http.Handler) *http.Response {
+	return doRequest(handler, http.MethodGet, "/metrics")
+}
+
 func doHealthRequest(handler http.Handler) *http.Response {
 	return doRequest(handler, http.MethodGet, "/-/health")
 }
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type RequestObserver interface {
+	ObserveRequest(route, method string, code int, duration time.Duration)
+}
+
+// instrument reports every request matching a route to the RequestObserver.
+// Routes are identified by their path template, so that path variables don't
+// create a new route for every value.
+func (h *Handler) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Path
+
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		recorder := statusRecorder{
+			ResponseWriter: w,
+			code:           http.StatusOK,
+		}
+
+		var response http.ResponseWriter = &recorder
+
+		// Hide the recorder behind a writer that can't flush if the original
+		// one can't, so that the events endpoint can detect it.
+		if _, ok := w.(http.Flusher); ok {
+			response = flushRecorder{&recorder}
+		}
+
+		start := time.Now()
+
+		next.ServeHTTP(response, r)
+
+		h.Requests.ObserveRequest(route, r.Method, recorder.code, time.Since(start))
+	})
+}
+
+// statusRecorder remembers the status code of the response. It implements
+// http.Hijacker, which is required to inject connection resets. See
+// flushRecorder for http.Flusher.
+type statusRecorder struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.code = code
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijacking not supported")
+	}
+
+	return hijacker.Hijack()
+}
+
+// flushRecorder is a statusRecorder implementing http.Flusher, which is
+// required by the events endpoint. It must only wrap a writer implementing
+// http.Flusher.
+type flushRecorder struct {
+	*statusRecorder
+}
+
+func (r flushRecorder) Flush() {
+	r.wroteHeader = true
+	r.ResponseWriter.(http.Flusher).Flush()
+}
@@ -0,0 +1,71 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/api"
+)
+
+type observedRequest struct {
+	route  string
+	method string
+	code   int
+}
+
+type mockRequestObserver struct {
+	requests []observedRequest
+}
+
+func (o *mockRequestObserver) ObserveRequest(route, method string, code int, duration time.Duration) {
+	o.requests = append(o.requests, observedRequest{route, method, code})
+}
+
+func TestHandlerInstrument(t *testing.T) {
+	var observer mockRequestObserver
+
+	handler := api.Handler{
+		Config: mockConfig{
+			doErrorsPercentage: func() float64 {
+				return 10
+			},
+		},
+		Requests: &observer,
+	}
+
+	doRequest(&handler, http.MethodGet, "/-/health")
+	doRequest(&handler, http.MethodGet, "/-/config/errors-percentage")
+	doRequestWithBody(&handler, http.MethodPut, "/-/config/errors-percentage", strings.NewReader("boom"))
+
+	want := []observedRequest{
+		{"/-/health", http.MethodGet, http.StatusOK},
+		{"/-/config/errors-percentage", http.MethodGet, http.StatusOK},
+		{"/-/config/errors-percentage", http.MethodPut, http.StatusBadRequest},
+	}
+
+	if len(observer.requests) != len(want) {
+		t.Fatalf("invalid number of requests: %v", observer.requests)
+	}
+
+	for i := range want {
+		if observer.requests[i] != want[i] {
+			t.Errorf("invalid request %d: got %v, want %v", i, observer.requests[i], want[i])
+		}
+	}
+}
+
+func TestHandlerSelfMetrics(t *testing.T) {
+	handler := api.Handler{
+		SelfMetrics: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("self"))
+		}),
+		MetricsOnly: true,
+	}
+
+	response := doRequest(&handler, http.MethodGet, "/-/metrics")
+
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, "self")
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/anomaly"
+	"github.com/francescomari/metrics-generator/internal/cardinality"
+	"github.com/francescomari/metrics-generator/internal/events"
+	"github.com/francescomari/metrics-generator/internal/faults"
+	"github.com/francescomari/metrics-generator/internal/gaps"
+	"github.com/francescomari/metrics-generator/internal/gauges"
+	"github.com/francescomari/metrics-generator/internal/health"
+	"github.com/francescomari/metrics-generator/internal/stats"
+	"github.com/gorilla/mux"
+)
+
+type nopHandler struct{}
+
+func (nopHandler) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+type nopReloader struct{}
+
+func (nopReloader) Reload() error {
+	return nil
+}
+
+type nopEventSource struct{}
+
+func (nopEventSource) Subscribe() *events.Subscription {
+	return nil
+}
+
+func (nopEventSource) Unsubscribe(*events.Subscription) {}
+
+type nopStats struct{}
+
+func (nopStats) Series(time.Time, time.Duration, time.Duration) []stats.Point {
+	return nil
+}
+
+func (nopStats) Summary(time.Time, time.Duration, []float64) stats.Summary {
+	return stats.Summary{}
+}
+
+type nopProbes struct{}
+
+func (nopProbes) Check(string, time.Time) (health.Result, error) {
+	return health.Result{}, nil
+}
+
+func (nopProbes) Fail(string, time.Time) error {
+	return nil
+}
+
+func (nopProbes) Clear(string) error {
+	return nil
+}
+
+type nopAnomalies struct{}
+
+func (nopAnomalies) Add(anomaly.Spec, time.Time) (anomaly.Anomaly, error) {
+	return anomaly.Anomaly{}, nil
+}
+
+func (nopAnomalies) Remove(string) bool {
+	return false
+}
+
+func (nopAnomalies) Clear() {}
+
+func (nopAnomalies) List(time.Time) []anomaly.Anomaly {
+	return nil
+}
+
+type nopCardinality struct{}
+
+func (nopCardinality) State(time.Time) cardinality.State {
+	return cardinality.State{}
+}
+
+func (nopCardinality) Set(int, float64, time.Time) error {
+	return nil
+}
+
+type nopGaps struct{}
+
+func (nopGaps) Hide(string, map[string]string, time.Time) (gaps.Rule, error) {
+	return gaps.Rule{}, nil
+}
+
+func (nopGaps) Show(string) bool {
+	return false
+}
+
+func (nopGaps) Pause(time.Time) {}
+
+func (nopGaps) Resume() {}
+
+func (nopGaps) State(time.Time) gaps.State {
+	return gaps.State{}
+}
+
+type nopGauges struct{}
+
+func (nopGauges) Put(gauges.Gauge) error {
+	return nil
+}
+
+func (nopGauges) Delete(string) bool {
+	return false
+}
+
+func (nopGauges) List(time.Time) []gauges.Value {
+	return nil
+}
+
+type nopRestarter struct{}
+
+func (nopRestarter) Restart(time.Time) {}
+
+type nopFaults struct{}
+
+func (nopFaults) Wrap(next http.Handler) http.Handler {
+	return next
+}
+
+func (nopFaults) State(time.Time) faults.State {
+	return faults.State{}
+}
+
+func (nopFaults) Set(faults.Spec, time.Time) error {
+	return nil
+}
+
+func (nopFaults) Clear() {}
+
+var routeVariablePattern = regexp.MustCompile(`{([^:}]+):[^}]*}`)
+
+// TestOpenAPICoversRoutes checks that every route registered by the handler is
+// documented in the OpenAPI document. Routes matching a path prefix are
+// expected to be documented with a path parameter after the prefix.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	var document struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+
+	if err := json.Unmarshal(openAPI, &document); err != nil {
+		t.Fatalf("parse OpenAPI document: %v", err)
+	}
+
+	handler := Handler{
+		Metrics:     nopHandler{},
+		SelfMetrics: nopHandler{},
+		Reloader:    nopReloader{},
+		Events:      nopEventSource{},
+		Stats:       nopStats{},
+		Probes:      nopProbes{},
+		Faults:      nopFaults{},
+		Restarter:   nopRestarter{},
+		Anomalies:   nopAnomalies{},
+		Cardinality: nopCardinality{},
+		Gaps:        nopGaps{},
+		Gauges:      nopGauges{},
+	}
+
+	handler.setupHandlers()
+
+	router := handler.handler.(*mux.Router)
+
+	var routes int
+
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		// Variables with a pattern, like {name:pattern}, are documented as
+		// {name}.
+		path = routeVariablePattern.ReplaceAllString(path, "{$1}")
+
+		operations, ok := document.Paths[path]
+
+		if !ok && strings.HasSuffix(path, "/") {
+			for documented, o := range document.Paths {
+				if strings.HasPrefix(documented, path+"{") {
+					operations, ok = o, true
+				}
+			}
+		}
+
+		if !ok {
+			t.Errorf("path %s is not documented", path)
+			return nil
+		}
+
+		for _, method := range methods {
+			routes++
+
+			if _, ok := operations[strings.ToLower(method)]; !ok {
+				t.Errorf("method %s of path %s is not documented", method, path)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+
+	if routes == 0 {
+		t.Fatalf("no routes found")
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/health"
+	"github.com/gorilla/mux"
+)
+
+type Probes interface {
+	Check(probe string, now time.Time) (health.Result, error)
+	Fail(probe string, until time.Time) error
+	Clear(probe string) error
+}
+
+func (h *Handler) setupProbeHandlers(router *mux.Router) {
+	if h.Probes == nil {
+		return
+	}
+
+	router.
+		Methods(http.MethodGet).
+		Path("/-/ready").
+		HandlerFunc(h.handleProbe(health.ProbeReady))
+
+	router.
+		Methods(http.MethodGet).
+		Path("/-/live").
+		HandlerFunc(h.handleProbe(health.ProbeLive))
+}
+
+func (h *Handler) setupProbesAdminHandlers(router *mux.Router) {
+	if h.Probes == nil {
+		return
+	}
+
+	router.
+		Methods(http.MethodGet).
+		Path("/-/probes").
+		HandlerFunc(h.handleGetProbes)
+
+	router.
+		Methods(http.MethodPut).
+		Path("/-/probes/{probe:ready|live}").
+		HandlerFunc(h.handleSetProbe)
+}
+
+// handleProbe responds with 200 if the probe succeeds, and 503 with the
+// reason of the failure otherwise. With "format=json" in the query, the
+// response contains the details of the check.
+func (h *Handler) handleProbe(probe string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := h.Probes.Check(probe, time.Now())
+		if err != nil {
+			h.httpError(w, r, http.StatusInternalServerError, "check probe: %v", err)
+			return
+		}
+
+		code := http.StatusOK
+
+		if !result.Healthy {
+			code = http.StatusServiceUnavailable
+		}
+
+		if r.URL.Query().Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+
+		if !result.Healthy {
+			http.Error(w, result.Reason, code)
+			return
+		}
+
+		fmt.Fprintln(w, "OK")
+	}
+}
+
+func (h *Handler) handleGetProbes(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+
+	data := make(map[string]health.Result)
+
+	for _, probe := range []string{health.ProbeReady, health.ProbeLive} {
+		result, err := h.Probes.Check(probe, now)
+		if err != nil {
+			h.httpError(w, r, http.StatusInternalServerError, "check probe: %v", err)
+			return
+		}
+
+		data[probe] = result
+	}
+
+	h.writeJSON(w, r, data)
+}
+
+// handleSetProbe forces a probe to fail if the body is "fail", and restores
+// its normal behaviour if the body is "pass". A forced failure lasts until
+// it is cleared or, if the "duration" query parameter is set, for that long.
+func (h *Handler) handleSetProbe(w http.ResponseWriter, r *http.Request) {
+	probe := mux.Vars(r)["probe"]
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.httpError(w, r, http.StatusInternalServerError, "read body: %v", err)
+		return
+	}
+
+	switch value := strings.TrimSpace(string(data)); value {
+	case "fail":
+		var until time.Time
+
+		if value := r.URL.Query().Get("duration"); value != "" {
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration <= 0 {
+				h.httpError(w, r, http.StatusBadRequest, "invalid duration %q", value)
+				return
+			}
+
+			until = time.Now().Add(duration)
+		}
+
+		err = h.Probes.Fail(probe, until)
+	case "pass":
+		err = h.Probes.Clear(probe)
+	default:
+		h.httpError(w, r, http.StatusBadRequest, "invalid value %q, must be fail or pass", value)
+		return
+	}
+
+	if err != nil {
+		h.httpError(w, r, http.StatusInternalServerError, "set probe: %v", err)
+		return
+	}
+
+	fmt.Fprintln(w, "OK")
+}
@@ -0,0 +1,118 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/health"
+)
+
+func TestHandlerProbesNotStarted(t *testing.T) {
+	handler := api.Handler{Probes: &health.Checker{}}
+
+	response := doRequest(&handler, http.MethodGet, "/-/ready")
+
+	checkStatusCode(t, response, http.StatusServiceUnavailable)
+	checkBody(t, response, "generator not started\n")
+}
+
+func TestHandlerProbes(t *testing.T) {
+	var checker health.Checker
+
+	checker.Beat(time.Now(), time.Minute)
+
+	handler := api.Handler{Probes: &checker}
+
+	for _, path := range []string{"/-/ready", "/-/live"} {
+		response := doRequest(&handler, http.MethodGet, path)
+
+		checkStatusCode(t, response, http.StatusOK)
+		checkBody(t, response, "OK\n")
+	}
+
+	response := doRequest(&handler, http.MethodGet, "/-/ready?format=json")
+
+	checkStatusCode(t, response, http.StatusOK)
+
+	var result health.Result
+
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if !result.Healthy || result.Probe != health.ProbeReady || result.LastTick.IsZero() {
+		t.Fatalf("invalid result: %+v", result)
+	}
+}
+
+func TestHandlerProbesForceFailure(t *testing.T) {
+	var checker health.Checker
+
+	checker.Beat(time.Now(), time.Minute)
+
+	handler := api.Handler{Probes: &checker}
+
+	response := doRequestWithBody(&handler, http.MethodPut, "/-/probes/live", strings.NewReader("fail"))
+	checkStatusCode(t, response, http.StatusOK)
+
+	response = doRequest(&handler, http.MethodGet, "/-/live")
+	checkStatusCode(t, response, http.StatusServiceUnavailable)
+	checkBody(t, response, "forced to fail\n")
+
+	checkStatusCode(t, doRequest(&handler, http.MethodGet, "/-/ready"), http.StatusOK)
+
+	response = doRequest(&handler, http.MethodGet, "/-/probes")
+	checkStatusCode(t, response, http.StatusOK)
+
+	var results map[string]health.Result
+
+	if err := json.NewDecoder(response.Body).Decode(&results); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	if !results["live"].Forced || results["ready"].Forced {
+		t.Fatalf("invalid results: %+v", results)
+	}
+
+	response = doRequestWithBody(&handler, http.MethodPut, "/-/probes/live", strings.NewReader("pass"))
+	checkStatusCode(t, response, http.StatusOK)
+
+	checkStatusCode(t, doRequest(&handler, http.MethodGet, "/-/live"), http.StatusOK)
+}
+
+func TestHandlerProbesForceFailureInvalid(t *testing.T) {
+	handler := api.Handler{Probes: &health.Checker{}}
+
+	tests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{"/-/probes/live", "boom", http.StatusBadRequest},
+		{"/-/probes/live?duration=boom", "fail", http.StatusBadRequest},
+		{"/-/probes/live?duration=-1s", "fail", http.StatusBadRequest},
+		{"/-/probes/boom", "fail", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		response := doRequestWithBody(&handler, http.MethodPut, test.path, strings.NewReader(test.body))
+
+		if response.StatusCode != test.code {
+			t.Errorf("invalid status code for %s: got %d, want %d", test.path, response.StatusCode, test.code)
+		}
+	}
+}
+
+func TestHandlerProbesMetricsOnly(t *testing.T) {
+	handler := api.Handler{
+		Probes:      &health.Checker{},
+		MetricsOnly: true,
+	}
+
+	checkStatusCode(t, doRequest(&handler, http.MethodGet, "/-/ready"), http.StatusServiceUnavailable)
+	checkStatusCode(t, doRequest(&handler, http.MethodGet, "/-/probes"), http.StatusNotFound)
+}
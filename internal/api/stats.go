@@ -0,0 +1,215 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/stats"
+	"github.com/gorilla/mux"
+)
+
+type Stats interface {
+	Series(now time.Time, window, step time.Duration) []stats.Point
+	Summary(now time.Time, window time.Duration, quantiles []float64) stats.Summary
+}
+
+// DefaultStatsWindows are the windows reported by the statistics endpoint if
+// neither the handler nor the request specify them.
+var DefaultStatsWindows = []time.Duration{time.Minute, 5 * time.Minute, time.Hour}
+
+// DefaultStatsQuantiles are the quantiles reported by the statistics endpoint
+// if the request doesn't specify them.
+var DefaultStatsQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+const (
+	defaultSeriesWindow = 15 * time.Minute
+	defaultSeriesStep   = 15 * time.Second
+	maxSeriesPoints     = 1000
+)
+
+func (h *Handler) setupStatsHandlers(router *mux.Router) {
+	if h.Stats == nil {
+		return
+	}
+
+	router.
+		Methods(http.MethodGet).
+		Path("/-/stats").
+		HandlerFunc(h.handleStats)
+
+	router.
+		Methods(http.MethodGet).
+		Path("/-/stats/series").
+		HandlerFunc(h.handleStatsSeries)
+}
+
+// handleStats reports exact statistics about the generated requests. The
+// optional "window" and "quantile" query parameters, which can be repeated or
+// contain comma-separated lists, select the windows and the quantiles to
+// report.
+func (h *Handler) handleStats(w http.ResponseWriter, r *http.Request) {
+	windows, err := parseWindows(r.URL.Query()["window"])
+	if err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse window: %v", err)
+		return
+	}
+
+	if windows == nil {
+		windows = h.StatsWindows
+	}
+
+	if windows == nil {
+		windows = DefaultStatsWindows
+	}
+
+	quantiles, err := parseQuantiles(r.URL.Query()["quantile"])
+	if err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse quantile: %v", err)
+		return
+	}
+
+	if quantiles == nil {
+		quantiles = DefaultStatsQuantiles
+	}
+
+	type Window struct {
+		stats.Summary
+		Window    string             `json:"window"`
+		Quantiles map[string]float64 `json:"quantiles"`
+	}
+
+	type Data struct {
+		Windows []Window `json:"windows"`
+	}
+
+	var data Data
+
+	now := time.Now()
+
+	for _, window := range windows {
+		summary := h.Stats.Summary(now, window, quantiles)
+
+		formatted := make(map[string]float64)
+
+		for q, v := range summary.Quantiles {
+			formatted[strconv.FormatFloat(q, 'f', -1, 64)] = v
+		}
+
+		data.Windows = append(data.Windows, Window{
+			Summary:   summary,
+			Window:    formatWindow(window),
+			Quantiles: formatted,
+		})
+	}
+
+	h.writeJSON(w, r, data)
+}
+
+func parseWindows(values []string) ([]time.Duration, error) {
+	var windows []time.Duration
+
+	for _, value := range splitValues(values) {
+		window, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if window < time.Second {
+			return nil, fmt.Errorf("window %v is less than one second", window)
+		}
+
+		windows = append(windows, window)
+	}
+
+	return windows, nil
+}
+
+func parseQuantiles(values []string) ([]float64, error) {
+	var quantiles []float64
+
+	for _, value := range splitValues(values) {
+		q, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		if q < 0 || q > 1 {
+			return nil, fmt.Errorf("quantile %v is not between 0 and 1", q)
+		}
+
+		quantiles = append(quantiles, q)
+	}
+
+	return quantiles, nil
+}
+
+func splitValues(values []string) []string {
+	var result []string
+
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				result = append(result, v)
+			}
+		}
+	}
+
+	return result
+}
+
+// formatWindow formats a window in the shortest unit that represents it
+// exactly, e.g. "5m" instead of "5m0s".
+func formatWindow(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	default:
+		return d.String()
+	}
+}
+
+func (h *Handler) handleStatsSeries(w http.ResponseWriter, r *http.Request) {
+	window, err := parseDurationParam(r, "window", defaultSeriesWindow)
+	if err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse window: %v", err)
+		return
+	}
+
+	step, err := parseDurationParam(r, "step", defaultSeriesStep)
+	if err != nil {
+		h.httpError(w, r, http.StatusBadRequest, "parse step: %v", err)
+		return
+	}
+
+	if step < time.Second {
+		h.httpError(w, r, http.StatusBadRequest, "step is less than one second")
+		return
+	}
+
+	if window < step {
+		h.httpError(w, r, http.StatusBadRequest, "window is less than step")
+		return
+	}
+
+	if window/step > maxSeriesPoints {
+		h.httpError(w, r, http.StatusBadRequest, "more than %d points requested", maxSeriesPoints)
+		return
+	}
+
+	h.writeJSON(w, r, h.Stats.Series(time.Now(), window, step))
+}
+
+func parseDurationParam(r *http.Request, name string, defaultValue time.Duration) (time.Duration, error) {
+	value := r.URL.Query().Get(name)
+
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	return time.ParseDuration(value)
+}
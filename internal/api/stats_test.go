@@ -0,0 +1,140 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/stats"
+)
+
+type mockStats struct {
+	doSeries  func(now time.Time, window, step time.Duration) []stats.Point
+	doSummary func(now time.Time, window time.Duration, quantiles []float64) stats.Summary
+}
+
+func (s mockStats) Series(now time.Time, window, step time.Duration) []stats.Point {
+	return s.doSeries(now, window, step)
+}
+
+func (s mockStats) Summary(now time.Time, window time.Duration, quantiles []float64) stats.Summary {
+	return s.doSummary(now, window, quantiles)
+}
+
+func TestHandlerStats(t *testing.T) {
+	var windows []time.Duration
+
+	handler := api.Handler{
+		Stats: mockStats{
+			doSummary: func(now time.Time, window time.Duration, quantiles []float64) stats.Summary {
+				windows = append(windows, window)
+
+				summary := stats.Summary{
+					Window:    window,
+					Start:     time.Unix(0, 0).UTC(),
+					End:       time.Unix(0, 0).UTC().Add(window),
+					Complete:  true,
+					Requests:  2,
+					Quantiles: make(map[float64]float64),
+				}
+
+				for _, q := range quantiles {
+					summary.Quantiles[q] = q * 10
+				}
+
+				return summary
+			},
+		},
+	}
+
+	response := doRequest(&handler, http.MethodGet, "/-/stats?window=1m,1h&quantile=0.5&quantile=0.99")
+
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, `{"windows":[`+
+		`{"start":"1970-01-01T00:00:00Z","end":"1970-01-01T00:01:00Z","complete":true,"requests":2,"rate":0,"errors":0,"errorRatio":0,"sum":0,"min":0,"max":0,"mean":0,"window":"1m","quantiles":{"0.5":5,"0.99":9.9}},`+
+		`{"start":"1970-01-01T00:00:00Z","end":"1970-01-01T01:00:00Z","complete":true,"requests":2,"rate":0,"errors":0,"errorRatio":0,"sum":0,"min":0,"max":0,"mean":0,"window":"1h","quantiles":{"0.5":5,"0.99":9.9}}`+
+		`]}`+"\n")
+
+	if len(windows) != 2 || windows[0] != time.Minute || windows[1] != time.Hour {
+		t.Fatalf("invalid windows: %v", windows)
+	}
+}
+
+func TestHandlerStatsDefaultWindows(t *testing.T) {
+	var windows []time.Duration
+
+	handler := api.Handler{
+		Stats: mockStats{
+			doSummary: func(now time.Time, window time.Duration, quantiles []float64) stats.Summary {
+				windows = append(windows, window)
+				return stats.Summary{}
+			},
+		},
+		StatsWindows: []time.Duration{30 * time.Second},
+	}
+
+	response := doRequest(&handler, http.MethodGet, "/-/stats")
+
+	checkStatusCode(t, response, http.StatusOK)
+
+	if len(windows) != 1 || windows[0] != 30*time.Second {
+		t.Fatalf("invalid windows: %v", windows)
+	}
+}
+
+func TestHandlerStatsInvalid(t *testing.T) {
+	handler := api.Handler{
+		Stats: mockStats{},
+	}
+
+	for _, query := range []string{"window=boom", "window=1ms", "quantile=boom", "quantile=2"} {
+		t.Run(query, func(t *testing.T) {
+			response := doRequest(&handler, http.MethodGet, "/-/stats?"+query)
+			checkStatusCode(t, response, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestHandlerStatsSeries(t *testing.T) {
+	var gotWindow, gotStep time.Duration
+
+	handler := api.Handler{
+		Stats: mockStats{
+			doSeries: func(now time.Time, window, step time.Duration) []stats.Point {
+				gotWindow, gotStep = window, step
+				return []stats.Point{{Time: time.Unix(0, 0).UTC(), Requests: 2, Rate: 1}}
+			},
+		},
+	}
+
+	response := doRequest(&handler, http.MethodGet, "/-/stats/series?window=5m&step=10s")
+
+	checkStatusCode(t, response, http.StatusOK)
+	checkBody(t, response, `[{"time":"1970-01-01T00:00:00Z","requests":2,"rate":1,"errors":0,"errorRatio":0,"p50":0,"p90":0,"p99":0}]`+"\n")
+
+	if gotWindow != 5*time.Minute || gotStep != 10*time.Second {
+		t.Fatalf("invalid parameters: window %v, step %v", gotWindow, gotStep)
+	}
+}
+
+func TestHandlerStatsSeriesInvalid(t *testing.T) {
+	handler := api.Handler{
+		Stats: mockStats{},
+	}
+
+	for _, query := range []string{"window=boom", "step=boom", "step=1ms", "window=1s&step=10s", "window=24h&step=1s"} {
+		t.Run(query, func(t *testing.T) {
+			response := doRequest(&handler, http.MethodGet, "/-/stats/series?"+query)
+			checkStatusCode(t, response, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestHandlerStatic(t *testing.T) {
+	handler := api.Handler{}
+
+	response := doRequest(&handler, http.MethodGet, "/-/static/charts.js")
+
+	checkStatusCode(t, response, http.StatusOK)
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Role is the set of operations a client is allowed to perform.
+type Role int
+
+const (
+	// RoleNone is the role of anonymous clients.
+	RoleNone Role = iota
+	// RoleReadOnly allows reading the configuration.
+	RoleReadOnly
+	// RoleAdmin allows reading and changing the configuration.
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleNone:
+		return "none"
+	case RoleReadOnly:
+		return "read-only"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+}
+
+func parseRole(value string) (Role, error) {
+	switch value {
+	case "read-only":
+		return RoleReadOnly, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return RoleNone, fmt.Errorf("invalid role %q", value)
+	}
+}
+
+type token struct {
+	token string
+	role  Role
+}
+
+type user struct {
+	username string
+	password string
+	role     Role
+}
+
+// Credentials authenticates requests with bearer tokens or basic
+// authentication.
+type Credentials struct {
+	tokens []token
+	users  []user
+}
+
+// Load reads the credentials from the YAML file at path. See Parse for the
+// format of the file.
+func Load(path string) (*Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %v", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse parses credentials from the content of a YAML file in the following
+// form, where role is either "read-only" or "admin":
+//
+//	tokens:
+//	  - token: secret
+//	    role: admin
+//	users:
+//	  - username: user
+//	    password: secret
+//	    role: read-only
+func Parse(data []byte) (*Credentials, error) {
+	var file struct {
+		Tokens []struct {
+			Token string `yaml:"token"`
+			Role  string `yaml:"role"`
+		} `yaml:"tokens"`
+		Users []struct {
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+			Role     string `yaml:"role"`
+		} `yaml:"users"`
+	}
+
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse YAML: %v", err)
+	}
+
+	var credentials Credentials
+
+	for i, t := range file.Tokens {
+		if t.Token == "" {
+			return nil, fmt.Errorf("token %d: empty token", i)
+		}
+
+		role, err := parseRole(t.Role)
+		if err != nil {
+			return nil, fmt.Errorf("token %d: %v", i, err)
+		}
+
+		credentials.tokens = append(credentials.tokens, token{
+			token: t.Token,
+			role:  role,
+		})
+	}
+
+	for i, u := range file.Users {
+		if u.Username == "" {
+			return nil, fmt.Errorf("user %d: empty username", i)
+		}
+
+		if u.Password == "" {
+			return nil, fmt.Errorf("user %d: empty password", i)
+		}
+
+		role, err := parseRole(u.Role)
+		if err != nil {
+			return nil, fmt.Errorf("user %d: %v", i, err)
+		}
+
+		credentials.users = append(credentials.users, user{
+			username: u.Username,
+			password: u.Password,
+			role:     role,
+		})
+	}
+
+	return &credentials, nil
+}
+
+// Authenticate returns the role of the client that sent the request. If the
+// request doesn't contain credentials, Authenticate returns RoleNone and no
+// error. If the request contains invalid credentials, Authenticate returns
+// RoleNone and an error.
+func (c *Credentials) Authenticate(r *http.Request) (Role, error) {
+	return c.AuthenticateHeader(r.Header.Get("Authorization"))
+}
+
+// AuthenticateHeader is like Authenticate, but accepts the value of the
+// Authorization header directly. It is used for protocols other than HTTP,
+// like gRPC, where the same credentials are sent as metadata.
+func (c *Credentials) AuthenticateHeader(header string) (Role, error) {
+	if header == "" {
+		return RoleNone, nil
+	}
+
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 {
+		return RoleNone, fmt.Errorf("invalid authorization header")
+	}
+
+	scheme, value := parts[0], parts[1]
+
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+		return c.authenticateToken(value)
+	case strings.EqualFold(scheme, "Basic"):
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return RoleNone, fmt.Errorf("invalid basic credentials")
+		}
+
+		credentials := strings.SplitN(string(decoded), ":", 2)
+		if len(credentials) != 2 {
+			return RoleNone, fmt.Errorf("invalid basic credentials")
+		}
+
+		return c.authenticateUser(credentials[0], credentials[1])
+	default:
+		return RoleNone, fmt.Errorf("unsupported authorization scheme")
+	}
+}
+
+func (c *Credentials) authenticateToken(value string) (Role, error) {
+	for _, t := range c.tokens {
+		if equal(t.token, value) {
+			return t.role, nil
+		}
+	}
+
+	return RoleNone, fmt.Errorf("invalid token")
+}
+
+func (c *Credentials) authenticateUser(username, password string) (Role, error) {
+	for _, u := range c.users {
+		if equal(u.username, username) && equal(u.password, password) {
+			return u.role, nil
+		}
+	}
+
+	return RoleNone, fmt.Errorf("invalid username or password")
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
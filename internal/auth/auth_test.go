@@ -0,0 +1,167 @@
+package auth_test
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/francescomari/metrics-generator/internal/auth"
+)
+
+const credentialsFile = `
+tokens:
+  - token: admin-token
+    role: admin
+  - token: reader-token
+    role: read-only
+users:
+  - username: admin
+    password: admin-password
+    role: admin
+`
+
+func TestAuthenticate(t *testing.T) {
+	credentials, err := auth.Parse([]byte(credentialsFile))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		username string
+		password string
+		role     auth.Role
+		invalid  bool
+	}{
+		{
+			name: "anonymous",
+			role: auth.RoleNone,
+		},
+		{
+			name:  "admin-token",
+			token: "admin-token",
+			role:  auth.RoleAdmin,
+		},
+		{
+			name:  "reader-token",
+			token: "reader-token",
+			role:  auth.RoleReadOnly,
+		},
+		{
+			name:    "invalid-token",
+			token:   "boom",
+			invalid: true,
+		},
+		{
+			name:     "admin-user",
+			username: "admin",
+			password: "admin-password",
+			role:     auth.RoleAdmin,
+		},
+		{
+			name:     "invalid-password",
+			username: "admin",
+			password: "boom",
+			invalid:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+
+			if test.token != "" {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+
+			if test.username != "" {
+				r.SetBasicAuth(test.username, test.password)
+			}
+
+			role, err := credentials.Authenticate(r)
+
+			if test.invalid {
+				if err == nil {
+					t.Fatalf("no error returned")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("authenticate: %v", err)
+			}
+
+			if role != test.role {
+				t.Fatalf("invalid role: got %v, want %v", role, test.role)
+			}
+		})
+	}
+}
+
+func TestAuthenticateUnsupportedScheme(t *testing.T) {
+	credentials, err := auth.Parse([]byte(credentialsFile))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Digest boom")
+
+	if _, err := credentials.Authenticate(r); err == nil {
+		t.Fatalf("no error returned")
+	}
+}
+
+func TestAuthenticateHeaderMalformed(t *testing.T) {
+	credentials, err := auth.Parse([]byte(credentialsFile))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	for _, header := range []string{"Bearer", "Basic !!!", "Basic " + base64.StdEncoding.EncodeToString([]byte("admin"))} {
+		if _, err := credentials.AuthenticateHeader(header); err == nil {
+			t.Errorf("no error returned for %q", header)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "invalid-yaml",
+			data: "tokens: [",
+		},
+		{
+			name: "empty-token",
+			data: "tokens:\n  - role: admin",
+		},
+		{
+			name: "invalid-token-role",
+			data: "tokens:\n  - token: a\n    role: boom",
+		},
+		{
+			name: "empty-username",
+			data: "users:\n  - password: a\n    role: admin",
+		},
+		{
+			name: "empty-password",
+			data: "users:\n  - username: a\n    role: admin",
+		},
+		{
+			name: "invalid-user-role",
+			data: "users:\n  - username: a\n    password: a\n    role: boom",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := auth.Parse([]byte(test.data)); err == nil {
+				t.Fatalf("no error returned")
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Package cardinality simulates a cardinality explosion, by exposing a metric
+// with a configurable number of unique label values that are periodically
+// retired and replaced by new ones.
+package cardinality
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultLabel is the name of the label with unique values.
+const DefaultLabel = "user_id"
+
+// MaxSeries is the maximum number of series that can be created.
+const MaxSeries = 1000000
+
+// MaxChurnRate is the maximum churn rate, enough to replace the maximum number
+// of series every second.
+const MaxChurnRate = MaxSeries
+
+// State is the current configuration and state of a Simulator.
+type State struct {
+	Label string
+
+	// Series is the configured number of series.
+	Series int
+
+	// ChurnRate is the number of series retired and replaced every second.
+	ChurnRate float64
+
+	// ActiveSeries is the number of series currently exposed.
+	ActiveSeries int
+
+	// ChurnedSeries is the number of series retired so far.
+	ChurnedSeries int
+}
+
+type series struct {
+	value   string
+	created time.Time
+}
+
+// Simulator exposes a counter with one series for every unique value of
+// Label. Its zero value exposes no series.
+type Simulator struct {
+	// Label is the name of the label with unique values. If empty,
+	// DefaultLabel is used.
+	Label string
+
+	mu        sync.Mutex
+	target    int
+	churnRate float64
+	series    []series
+	lastID    int
+	churned   int
+	pending   float64
+	last      time.Time
+}
+
+// Set changes the number of series and the churn rate. Series are created or
+// retired immediately to match the new number.
+func (s *Simulator) Set(n int, churnRate float64, now time.Time) error {
+	if n < 0 || n > MaxSeries {
+		return fmt.Errorf("series must be between 0 and %d", MaxSeries)
+	}
+
+	if !(churnRate >= 0 && churnRate <= MaxChurnRate) {
+		return fmt.Errorf("churn rate must be between 0 and %d", MaxChurnRate)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.advance(now)
+
+	s.target = n
+	s.churnRate = churnRate
+
+	if len(s.series) > n {
+		s.retire(len(s.series) - n)
+	}
+
+	for len(s.series) < n {
+		s.create(now)
+	}
+
+	return nil
+}
+
+// State returns the state of the simulator at time now.
+func (s *Simulator) State(now time.Time) State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.advance(now)
+
+	return State{
+		Label:         s.label(),
+		Series:        s.target,
+		ChurnRate:     s.churnRate,
+		ActiveSeries:  len(s.series),
+		ChurnedSeries: s.churned,
+	}
+}
+
+func (s *Simulator) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.desc()
+}
+
+func (s *Simulator) Collect(ch chan<- prometheus.Metric) {
+	desc := s.desc()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	s.advance(now)
+
+	for _, series := range s.series {
+		value := float64(int64(now.Sub(series.created).Seconds()))
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, series.value)
+	}
+}
+
+func (s *Simulator) desc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		"metrics_generator_user_requests_total",
+		"Simulated requests by user, with one series for every active label value",
+		[]string{s.label()}, nil,
+	)
+}
+
+func (s *Simulator) label() string {
+	if s.Label == "" {
+		return DefaultLabel
+	}
+
+	return s.Label
+}
+
+// advance retires and replaces the series churned between the last call and
+// now.
+func (s *Simulator) advance(now time.Time) {
+	if !s.last.IsZero() && now.After(s.last) && s.churnRate > 0 && len(s.series) > 0 {
+		s.pending += s.churnRate * now.Sub(s.last).Seconds()
+	}
+
+	s.last = now
+
+	// Churning more than the whole set replaces it only once. Clamping the
+	// pending series also keeps the conversion below in range.
+	if s.pending > float64(len(s.series)) {
+		s.pending = float64(len(s.series))
+	}
+
+	n := int(s.pending)
+
+	if n == 0 {
+		return
+	}
+
+	s.pending -= float64(n)
+
+	s.retire(n)
+
+	for i := 0; i < n; i++ {
+		s.create(now)
+	}
+}
+
+func (s *Simulator) retire(n int) {
+	s.series = append(s.series[:0:0], s.series[n:]...)
+	s.churned += n
+}
+
+func (s *Simulator) create(now time.Time) {
+	s.lastID++
+
+	s.series = append(s.series, series{
+		value:   strconv.Itoa(s.lastID),
+		created: now,
+	})
+}
@@ -0,0 +1,124 @@
+package cardinality_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/cardinality"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSeries(t *testing.T) {
+	var simulator cardinality.Simulator
+
+	now := time.Now()
+
+	if err := simulator.Set(100, 0, now); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	if n := testutil.CollectAndCount(&simulator); n != 100 {
+		t.Fatalf("invalid number of series: %d", n)
+	}
+
+	if err := simulator.Set(10, 0, now); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	state := simulator.State(now)
+
+	if state.ActiveSeries != 10 || state.ChurnedSeries != 90 || state.Label != cardinality.DefaultLabel {
+		t.Fatalf("invalid state: %+v", state)
+	}
+}
+
+func TestChurn(t *testing.T) {
+	var simulator cardinality.Simulator
+
+	now := time.Unix(1000, 0)
+
+	if err := simulator.Set(100, 2.5, now); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	state := simulator.State(now.Add(10 * time.Second))
+
+	if state.ActiveSeries != 100 || state.ChurnedSeries != 25 {
+		t.Fatalf("invalid state: %+v", state)
+	}
+
+	// Churning more series than the active ones replaces the whole set.
+	state = simulator.State(now.Add(time.Hour))
+
+	if state.ActiveSeries != 100 || state.ChurnedSeries != 125 {
+		t.Fatalf("invalid state: %+v", state)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	simulator := cardinality.Simulator{Label: "session_id"}
+
+	if err := simulator.Set(1, 0, time.Now()); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&simulator)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	if len(families) != 1 || families[0].Metric[0].Label[0].GetName() != "session_id" {
+		t.Fatalf("invalid metrics: %v", families)
+	}
+}
+
+func TestSetInvalid(t *testing.T) {
+	var simulator cardinality.Simulator
+
+	if err := simulator.Set(-1, 0, time.Now()); err == nil {
+		t.Fatalf("no error for negative series")
+	}
+
+	if err := simulator.Set(cardinality.MaxSeries+1, 0, time.Now()); err == nil {
+		t.Fatalf("no error for too many series")
+	}
+
+	if err := simulator.Set(10, -1, time.Now()); err == nil {
+		t.Fatalf("no error for negative churn rate")
+	}
+
+	for _, rate := range []float64{cardinality.MaxChurnRate + 1, 1e300, math.Inf(1), math.NaN()} {
+		if err := simulator.Set(10, rate, time.Now()); err == nil {
+			t.Fatalf("no error for churn rate %v", rate)
+		}
+	}
+}
+
+func TestChurnMaxRate(t *testing.T) {
+	var simulator cardinality.Simulator
+
+	now := time.Unix(1000, 0)
+
+	if err := simulator.Set(10, cardinality.MaxChurnRate, now); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	// A year at the maximum rate churns far more series than can be
+	// converted to an int on 32-bit platforms, but replaces the set once.
+	state := simulator.State(now.Add(365 * 24 * time.Hour))
+
+	if state.ActiveSeries != 10 || state.ChurnedSeries != 10 {
+		t.Fatalf("invalid state: %+v", state)
+	}
+
+	state = simulator.State(now.Add(365*24*time.Hour + time.Second))
+
+	if state.ActiveSeries != 10 || state.ChurnedSeries != 20 {
+		t.Fatalf("invalid state: %+v", state)
+	}
+}
@@ -0,0 +1,134 @@
+package configfile
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads the YAML file at path and returns its settings. The file must
+// contain a single mapping whose keys are setting names. Values must be
+// scalars or sequences of scalars. Sequences are returned as a comma-separated
+// list of their elements.
+func Load(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %v", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse parses settings from the content of a YAML file. See Load for the
+// expected format.
+func Parse(data []byte) (map[string]string, error) {
+	var document yaml.Node
+
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("parse YAML: %v", err)
+	}
+
+	settings := make(map[string]string)
+
+	if len(document.Content) == 0 {
+		return settings, nil
+	}
+
+	root := document.Content[0]
+
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: not a mapping", root.Line)
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: key is not a scalar", key.Line)
+		}
+
+		if _, ok := settings[key.Value]; ok {
+			return nil, fmt.Errorf("line %d: duplicate setting %q", key.Line, key.Value)
+		}
+
+		parsed, err := parseValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: setting %q: %v", value.Line, key.Value, err)
+		}
+
+		settings[key.Value] = parsed
+	}
+
+	return settings, nil
+}
+
+func parseValue(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value, nil
+	case yaml.SequenceNode:
+		var values []string
+
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("sequence item is not a scalar")
+			}
+
+			values = append(values, item.Value)
+		}
+
+		return strings.Join(values, ","), nil
+	default:
+		return "", fmt.Errorf("value is not a scalar or a sequence")
+	}
+}
+
+// Watcher polls a file and notifies when its content changes. Polling the
+// content rather than relying on file system events makes the Watcher work
+// with files that are replaced by swapping symbolic links, like Kubernetes
+// ConfigMap volumes do.
+type Watcher struct {
+	Path     string
+	Interval time.Duration
+	OnChange func()
+}
+
+// Run polls the file until the context is cancelled. The content of the file
+// when Run is called is considered the initial state, and OnChange is called
+// only for subsequent changes. Errors reading the file are treated as a
+// change, so that OnChange can report them.
+func (w *Watcher) Run(ctx context.Context) error {
+	last := w.checksum()
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if current := w.checksum(); !bytes.Equal(current, last) {
+				last = current
+				w.OnChange()
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *Watcher) checksum() []byte {
+	data, err := os.ReadFile(w.Path)
+	if err != nil {
+		return nil
+	}
+
+	sum := sha256.Sum256(data)
+
+	return sum[:]
+}
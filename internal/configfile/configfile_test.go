@@ -0,0 +1,151 @@
+package configfile_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/configfile"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte(`
+addr: ":9090"
+duration-min: 2
+errors-percentage: 12.5
+requests-hour:
+  - 100
+  - 200
+`)
+
+	settings, err := configfile.Parse(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	want := map[string]string{
+		"addr":              ":9090",
+		"duration-min":      "2",
+		"errors-percentage": "12.5",
+		"requests-hour":     "100,200",
+	}
+
+	if diff := cmp.Diff(want, settings); diff != "" {
+		t.Fatalf("invalid settings:\n%s", diff)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	settings, err := configfile.Parse(nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if len(settings) != 0 {
+		t.Fatalf("unexpected settings: %v", settings)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "invalid-yaml",
+			data: "addr: [",
+		},
+		{
+			name: "not-a-mapping",
+			data: "- addr",
+		},
+		{
+			name: "duplicate",
+			data: "addr: a\naddr: b",
+		},
+		{
+			name: "nested-mapping",
+			data: "addr:\n  host: localhost",
+		},
+		{
+			name: "nested-sequence",
+			data: "addr:\n  - [a, b]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := configfile.Parse([]byte(test.data)); err == nil {
+				t.Fatalf("no error returned")
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := configfile.Load(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Fatalf("no error returned")
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	writeFile(t, path, "addr: a")
+
+	changes := make(chan struct{}, 10)
+
+	watcher := configfile.Watcher{
+		Path:     path,
+		Interval: time.Millisecond,
+		OnChange: func() {
+			changes <- struct{}{}
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- watcher.Run(ctx)
+	}()
+
+	// The watcher might read the file after it is changed, and take the
+	// change for the initial content. Keep changing the file until a change
+	// is detected.
+	timeout := time.After(5 * time.Second)
+
+	for i := 0; ; i++ {
+		writeFile(t, path, fmt.Sprintf("addr: %d", i))
+
+		select {
+		case <-changes:
+		case <-time.After(10 * time.Millisecond):
+			continue
+		case <-timeout:
+			t.Fatalf("change not detected")
+		}
+
+		break
+	}
+
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("invalid error: %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+}
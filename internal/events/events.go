@@ -0,0 +1,123 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// TypeRequest is the type of the events describing a simulated request.
+	TypeRequest = "request"
+	// TypeConfig is the type of the events describing a configuration change.
+	TypeConfig = "config"
+)
+
+// Event is something that happened in the generator.
+type Event struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Request *Request  `json:"request,omitempty"`
+	Config  *Config   `json:"config,omitempty"`
+}
+
+// Request describes a simulated request.
+type Request struct {
+	Duration float64 `json:"duration"`
+	Error    bool    `json:"error"`
+
+	// TraceID is the ID of the trace of the request, or of the trace its
+	// exemplar points to, if any.
+	TraceID string `json:"traceId,omitempty"`
+
+	// Labels are the labels of the exemplar attached to the observations of
+	// the request, if any, like its trace ID.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Config describes the configuration after a change.
+type Config struct {
+	MinDuration      int     `json:"minDuration"`
+	MaxDuration      int     `json:"maxDuration"`
+	ErrorsPercentage float64 `json:"errorsPercentage"`
+	RequestsHour     int     `json:"requestsHour"`
+}
+
+// DefaultBufferSize is the number of events buffered for every subscription
+// if the Broker doesn't specify a buffer size.
+const DefaultBufferSize = 1000
+
+// Broker delivers published events to every subscription. Publishing never
+// blocks: if the buffer of a subscription is full, the event is dropped for
+// that subscription only.
+type Broker struct {
+	BufferSize int
+
+	mu            sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+}
+
+// Subscription receives the events published by a Broker.
+type Subscription struct {
+	c       chan Event
+	dropped uint64
+}
+
+// Events returns the channel delivering the events.
+func (s *Subscription) Events() <-chan Event {
+	return s.c
+}
+
+// Dropped returns the number of events dropped because the buffer of the
+// subscription was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Subscribe creates a new subscription. The subscription must be released
+// with Unsubscribe.
+func (b *Broker) Subscribe() *Subscription {
+	size := b.BufferSize
+
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+
+	s := &Subscription{
+		c: make(chan Event, size),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscriptions == nil {
+		b.subscriptions = make(map[*Subscription]struct{})
+	}
+
+	b.subscriptions[s] = struct{}{}
+
+	return s
+}
+
+// Unsubscribe releases a subscription. No more events are delivered to the
+// subscription after Unsubscribe returns.
+func (b *Broker) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscriptions, s)
+}
+
+// Publish delivers an event to every subscription.
+func (b *Broker) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subscriptions {
+		select {
+		case s.c <- e:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/francescomari/metrics-generator/internal/events"
+)
+
+func TestBrokerPublish(t *testing.T) {
+	var broker events.Broker
+
+	first := broker.Subscribe()
+	defer broker.Unsubscribe(first)
+
+	second := broker.Subscribe()
+	defer broker.Unsubscribe(second)
+
+	broker.Publish(events.Event{Type: events.TypeRequest})
+
+	for _, s := range []*events.Subscription{first, second} {
+		select {
+		case e := <-s.Events():
+			if e.Type != events.TypeRequest {
+				t.Fatalf("invalid event type: %v", e.Type)
+			}
+		default:
+			t.Fatalf("event not delivered")
+		}
+	}
+}
+
+func TestBrokerSlowSubscriber(t *testing.T) {
+	broker := events.Broker{
+		BufferSize: 2,
+	}
+
+	s := broker.Subscribe()
+	defer broker.Unsubscribe(s)
+
+	for i := 0; i < 5; i++ {
+		broker.Publish(events.Event{Type: events.TypeRequest})
+	}
+
+	if got := len(s.Events()); got != 2 {
+		t.Fatalf("invalid number of buffered events: %d", got)
+	}
+
+	if got := s.Dropped(); got != 3 {
+		t.Fatalf("invalid number of dropped events: %d", got)
+	}
+}
+
+func TestBrokerUnsubscribe(t *testing.T) {
+	var broker events.Broker
+
+	s := broker.Subscribe()
+
+	broker.Unsubscribe(s)
+	broker.Publish(events.Event{Type: events.TypeRequest})
+
+	if got := len(s.Events()); got != 0 {
+		t.Fatalf("event delivered after unsubscribe")
+	}
+}
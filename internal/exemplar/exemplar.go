@@ -0,0 +1,53 @@
+// Package exemplar creates exemplars for the simulated requests, carrying
+// synthetic trace and span IDs.
+package exemplar
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Label names of the exemplars.
+const (
+	TraceIDLabel = "trace_id"
+	SpanIDLabel  = "span_id"
+)
+
+// Sampler returns an exemplar for a fraction of the simulated requests.
+type Sampler struct {
+	// Rate is the fraction of the requests with an exemplar, between 0 and
+	// 1.
+	Rate float64
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// Exemplar returns the labels of the exemplar of a simulated request, or nil
+// if the request is not sampled. Trace IDs are 16 random bytes and span IDs 8
+// random bytes, hex encoded as in W3C Trace Context.
+func (s *Sampler) Exemplar() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rand == nil {
+		s.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	if s.Rate <= 0 || s.rand.Float64() >= s.Rate {
+		return nil
+	}
+
+	return map[string]string{
+		TraceIDLabel: s.randomID(16),
+		SpanIDLabel:  s.randomID(8),
+	}
+}
+
+func (s *Sampler) randomID(n int) string {
+	id := make([]byte, n)
+	s.rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,35 @@
+package exemplar_test
+
+import (
+	"testing"
+
+	"github.com/francescomari/metrics-generator/internal/exemplar"
+)
+
+func TestSamplerAlways(t *testing.T) {
+	sampler := exemplar.Sampler{Rate: 1}
+
+	labels := sampler.Exemplar()
+
+	if len(labels[exemplar.TraceIDLabel]) != 32 {
+		t.Fatalf("invalid trace ID: %q", labels[exemplar.TraceIDLabel])
+	}
+
+	if len(labels[exemplar.SpanIDLabel]) != 16 {
+		t.Fatalf("invalid span ID: %q", labels[exemplar.SpanIDLabel])
+	}
+
+	if other := sampler.Exemplar(); other[exemplar.TraceIDLabel] == labels[exemplar.TraceIDLabel] {
+		t.Fatalf("trace ID reused: %q", other[exemplar.TraceIDLabel])
+	}
+}
+
+func TestSamplerNever(t *testing.T) {
+	sampler := exemplar.Sampler{Rate: 0}
+
+	for i := 0; i < 100; i++ {
+		if labels := sampler.Exemplar(); labels != nil {
+			t.Fatalf("request sampled: %v", labels)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Package faults injects faults in the responses of the metrics endpoint, to
+// simulate a misbehaving scrape target.
+package faults
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Spec describes the faults to inject. Percentages are of the requests
+// served while the faults are active, and their sum must not exceed 100.
+type Spec struct {
+	// Latency is added to every response. A random duration up to
+	// LatencyJitter is added on top of it.
+	Latency       time.Duration
+	LatencyJitter time.Duration
+
+	// ErrorPercentage is the percentage of responses replaced by an error
+	// with status code ErrorCode, 500 by default.
+	ErrorPercentage float64
+	ErrorCode       int
+
+	// ResetPercentage is the percentage of connections reset before a
+	// response is sent.
+	ResetPercentage float64
+
+	// TruncatePercentage is the percentage of responses cut in half.
+	TruncatePercentage float64
+
+	// MalformedPercentage is the percentage of responses containing a line
+	// that is not valid in the exposition format.
+	MalformedPercentage float64
+
+	// UnavailablePeriod and UnavailableDuration make the endpoint respond
+	// with 503 for UnavailableDuration at the beginning of every
+	// UnavailablePeriod.
+	UnavailablePeriod   time.Duration
+	UnavailableDuration time.Duration
+
+	// Delay is how long after being set the faults become active.
+	Delay time.Duration
+
+	// Duration is how long the faults stay active. If zero, they stay active
+	// until they are cleared.
+	Duration time.Duration
+}
+
+// Validate returns an error if the spec is invalid.
+func (s Spec) Validate() error {
+	percentages := map[string]float64{
+		"error percentage":     s.ErrorPercentage,
+		"reset percentage":     s.ResetPercentage,
+		"truncate percentage":  s.TruncatePercentage,
+		"malformed percentage": s.MalformedPercentage,
+	}
+
+	var total float64
+
+	for name, value := range percentages {
+		if value < 0 || value > 100 {
+			return fmt.Errorf("%s must be between 0 and 100", name)
+		}
+
+		total += value
+	}
+
+	if total > 100 {
+		return fmt.Errorf("the sum of the percentages must not exceed 100")
+	}
+
+	durations := map[string]time.Duration{
+		"latency":              s.Latency,
+		"latency jitter":       s.LatencyJitter,
+		"unavailable period":   s.UnavailablePeriod,
+		"unavailable duration": s.UnavailableDuration,
+		"delay":                s.Delay,
+		"duration":             s.Duration,
+	}
+
+	for name, value := range durations {
+		if value < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+
+	if s.ErrorCode != 0 && (s.ErrorCode < 500 || s.ErrorCode > 599) {
+		return fmt.Errorf("error code must be between 500 and 599")
+	}
+
+	if s.UnavailableDuration > s.UnavailablePeriod {
+		return fmt.Errorf("unavailable duration must not be greater than the unavailable period")
+	}
+
+	if s.UnavailableDuration == 0 && s.UnavailablePeriod > 0 {
+		return fmt.Errorf("unavailable duration is required with an unavailable period")
+	}
+
+	return nil
+}
+
+// State is the current configuration of an Injector.
+type State struct {
+	Spec Spec
+
+	// Set is true if faults were set and not cleared.
+	Set bool
+
+	// Start and End delimit the window in which the faults are active. End
+	// is zero if the faults stay active until they are cleared.
+	Start time.Time
+	End   time.Time
+
+	// Active is true if the faults are being injected.
+	Active bool
+}
+
+// Injector wraps an HTTP handler and injects the faults described by a Spec
+// while they are active.
+type Injector struct {
+	mu    sync.Mutex
+	spec  Spec
+	set   bool
+	start time.Time
+	end   time.Time
+}
+
+// Set replaces the faults, which become active after the delay of the spec.
+func (i *Injector) Set(spec Spec, now time.Time) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.spec = spec
+	i.set = true
+	i.start = now.Add(spec.Delay)
+	i.end = time.Time{}
+
+	if spec.Duration > 0 {
+		i.end = i.start.Add(spec.Duration)
+	}
+
+	return nil
+}
+
+// Clear removes the faults.
+func (i *Injector) Clear() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.spec = Spec{}
+	i.set = false
+	i.start = time.Time{}
+	i.end = time.Time{}
+}
+
+// State returns the configuration of the injector at time now.
+func (i *Injector) State(now time.Time) State {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.state(now)
+}
+
+func (i *Injector) state(now time.Time) State {
+	return State{
+		Spec:   i.spec,
+		Set:    i.set,
+		Start:  i.start,
+		End:    i.end,
+		Active: i.set && !now.Before(i.start) && (i.end.IsZero() || now.Before(i.end)),
+	}
+}
+
+// Wrap returns a handler injecting faults in the responses of next.
+func (i *Injector) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := i.State(time.Now())
+
+		if !state.Active {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		spec := state.Spec
+
+		if spec.UnavailablePeriod > 0 && time.Since(state.Start)%spec.UnavailablePeriod < spec.UnavailableDuration {
+			http.Error(w, "unavailable (injected fault)", http.StatusServiceUnavailable)
+			return
+		}
+
+		latency := spec.Latency
+
+		if spec.LatencyJitter > 0 {
+			latency += time.Duration(rand.Int63n(int64(spec.LatencyJitter) + 1))
+		}
+
+		if latency > 0 {
+			timer := time.NewTimer(latency)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		roll := rand.Float64() * 100
+
+		switch {
+		case roll < spec.ErrorPercentage:
+			code := spec.ErrorCode
+
+			if code == 0 {
+				code = http.StatusInternalServerError
+			}
+
+			http.Error(w, "injected fault", code)
+		case roll < spec.ErrorPercentage+spec.ResetPercentage:
+			resetConnection(w)
+		case roll < spec.ErrorPercentage+spec.ResetPercentage+spec.TruncatePercentage:
+			serveTruncated(w, r, next)
+		case roll < spec.ErrorPercentage+spec.ResetPercentage+spec.TruncatePercentage+spec.MalformedPercentage:
+			serveMalformed(w, r, next)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// resetConnection closes the connection without sending a response. TCP
+// connections are closed with a RST instead of a FIN.
+func resetConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+
+	conn.Close()
+}
+
+// serveTruncated sends the headers of the full response, including its
+// length, but only half of the body before closing the connection.
+func serveTruncated(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	recorder := record(r, next)
+
+	copyHeader(w.Header(), recorder.header)
+	w.Header().Set("Content-Length", strconv.Itoa(recorder.body.Len()))
+	w.WriteHeader(recorder.code)
+	w.Write(recorder.body.Bytes()[:recorder.body.Len()/2])
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	panic(http.ErrAbortHandler)
+}
+
+// malformedLine is not valid in the text exposition format.
+const malformedLine = "metrics_generator_malformed{fault=\"injected\" 1 boom\n"
+
+// serveMalformed inserts an invalid line in the middle of the response.
+func serveMalformed(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	recorder := record(r, next)
+
+	body := recorder.body.Bytes()
+
+	position := bytes.IndexByte(body[len(body)/2:], '\n')
+
+	if position < 0 {
+		position = len(body)
+	} else {
+		position += len(body)/2 + 1
+	}
+
+	copyHeader(w.Header(), recorder.header)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(recorder.code)
+	w.Write(body[:position])
+	w.Write([]byte(malformedLine))
+	w.Write(body[position:])
+}
+
+type responseRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.code = code
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+// record serves the request with next and returns the response. The response
+// is requested uncompressed, so that it can be altered.
+func record(r *http.Request, next http.Handler) *responseRecorder {
+	r = r.Clone(r.Context())
+	r.Header.Del("Accept-Encoding")
+
+	recorder := responseRecorder{
+		header: make(http.Header),
+		code:   http.StatusOK,
+	}
+
+	next.ServeHTTP(&recorder, r)
+
+	return &recorder
+}
+
+func copyHeader(dst, src http.Header) {
+	for name, values := range src {
+		dst[name] = values
+	}
+}
@@ -0,0 +1,219 @@
+package faults_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/faults"
+)
+
+const exposition = "# TYPE a counter\na 1\n# TYPE b counter\nb 2\n"
+
+func newServer(t *testing.T, injector *faults.Injector) *httptest.Server {
+	t.Helper()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, exposition)
+	})
+
+	server := httptest.NewServer(injector.Wrap(next))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func get(t *testing.T, url string) (*http.Response, string, error) {
+	t.Helper()
+
+	response, err := http.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+
+	return response, string(data), err
+}
+
+func TestInactive(t *testing.T) {
+	var injector faults.Injector
+
+	server := newServer(t, &injector)
+
+	if err := injector.Set(faults.Spec{ErrorPercentage: 100, Delay: time.Hour}, time.Now()); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	_, body, err := get(t, server.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	if body != exposition {
+		t.Fatalf("invalid body: %q", body)
+	}
+}
+
+func TestError(t *testing.T) {
+	var injector faults.Injector
+
+	server := newServer(t, &injector)
+
+	if err := injector.Set(faults.Spec{ErrorPercentage: 100, ErrorCode: 502}, time.Now()); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	response, _, err := get(t, server.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadGateway {
+		t.Fatalf("invalid status code: %d", response.StatusCode)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	var injector faults.Injector
+
+	server := newServer(t, &injector)
+
+	if err := injector.Set(faults.Spec{Latency: 100 * time.Millisecond}, time.Now()); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	start := time.Now()
+
+	if _, _, err := get(t, server.URL); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("latency not injected: %v", elapsed)
+	}
+}
+
+func TestReset(t *testing.T) {
+	var injector faults.Injector
+
+	server := newServer(t, &injector)
+
+	if err := injector.Set(faults.Spec{ResetPercentage: 100}, time.Now()); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	if _, _, err := get(t, server.URL); err == nil {
+		t.Fatalf("no error returned")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	var injector faults.Injector
+
+	server := newServer(t, &injector)
+
+	if err := injector.Set(faults.Spec{TruncatePercentage: 100}, time.Now()); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	if _, body, err := get(t, server.URL); err == nil {
+		t.Fatalf("no error returned, body %q", body)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	var injector faults.Injector
+
+	server := newServer(t, &injector)
+
+	if err := injector.Set(faults.Spec{MalformedPercentage: 100}, time.Now()); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	_, body, err := get(t, server.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	if !strings.Contains(body, "metrics_generator_malformed{") || len(body) <= len(exposition) {
+		t.Fatalf("invalid body: %q", body)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	var injector faults.Injector
+
+	server := newServer(t, &injector)
+
+	spec := faults.Spec{
+		UnavailablePeriod:   time.Hour,
+		UnavailableDuration: time.Minute,
+	}
+
+	if err := injector.Set(spec, time.Now()); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	response, _, err := get(t, server.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	if response.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("invalid status code: %d", response.StatusCode)
+	}
+}
+
+func TestState(t *testing.T) {
+	var injector faults.Injector
+
+	now := time.Unix(1000, 0)
+
+	if err := injector.Set(faults.Spec{ErrorPercentage: 10, Delay: time.Minute, Duration: time.Minute}, now); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		active  bool
+	}{
+		{0, false},
+		{time.Minute, true},
+		{2 * time.Minute, false},
+	}
+
+	for _, test := range tests {
+		if state := injector.State(now.Add(test.elapsed)); state.Active != test.active {
+			t.Errorf("invalid state after %v: %+v", test.elapsed, state)
+		}
+	}
+
+	injector.Clear()
+
+	if state := injector.State(now.Add(time.Minute)); state.Set || state.Active {
+		t.Fatalf("faults not cleared: %+v", state)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []faults.Spec{
+		{ErrorPercentage: -1},
+		{ErrorPercentage: 101},
+		{ErrorPercentage: 60, ResetPercentage: 60},
+		{ErrorCode: 404},
+		{Latency: -time.Second},
+		{UnavailablePeriod: time.Minute},
+		{UnavailablePeriod: time.Minute, UnavailableDuration: time.Hour},
+	}
+
+	for _, spec := range invalid {
+		if err := spec.Validate(); err == nil {
+			t.Errorf("no error returned for %+v", spec)
+		}
+	}
+}
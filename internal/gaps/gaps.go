@@ -0,0 +1,255 @@
+// Package gaps creates gaps in the metrics, by hiding series from the metrics
+// endpoint and by pausing the observation of simulated requests.
+package gaps
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+// Rule hides the series of a metric matching a set of labels.
+type Rule struct {
+	ID string
+
+	// Metric is the name of the metric family, for example the name of a
+	// histogram without the _bucket, _sum or _count suffix.
+	Metric string
+
+	// Labels must all be equal to the labels of a series for the series to
+	// be hidden. If empty, every series of the metric is hidden.
+	Labels map[string]string
+
+	// Until is when the rule expires, zero if it lasts until it is removed.
+	Until time.Time
+}
+
+func (r Rule) expired(now time.Time) bool {
+	return !r.Until.IsZero() && !now.Before(r.Until)
+}
+
+func (r Rule) matches(family *dto.MetricFamily, metric *dto.Metric) bool {
+	if family.GetName() != r.Metric {
+		return false
+	}
+
+	for name, value := range r.Labels {
+		if labelValue(metric, name) != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func labelValue(metric *dto.Metric, name string) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+
+	return ""
+}
+
+// State is the current state of Gaps.
+type State struct {
+	// Paused is true if the observation of simulated requests is paused.
+	Paused bool
+
+	// PausedUntil is when the pause ends, zero if it lasts until it is
+	// resumed.
+	PausedUntil time.Time
+
+	// Hidden are the rules hiding series, in the order they were added.
+	Hidden []Rule
+}
+
+// Gaps holds the series to hide and whether observation is paused. The zero
+// value hides nothing and is not paused.
+type Gaps struct {
+	mu          sync.Mutex
+	lastID      int
+	rules       []Rule
+	paused      bool
+	pausedUntil time.Time
+}
+
+// Hide hides the series of a metric matching the labels until the given
+// time, or until the rule is removed if until is zero.
+func (g *Gaps) Hide(metric string, labels map[string]string, until time.Time) (Rule, error) {
+	if !model.IsValidMetricName(model.LabelValue(metric)) {
+		return Rule{}, fmt.Errorf("invalid metric name %q", metric)
+	}
+
+	for name := range labels {
+		if !model.LabelName(name).IsValid() {
+			return Rule{}, fmt.Errorf("invalid label name %q", name)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.lastID++
+
+	rule := Rule{
+		ID:     strconv.Itoa(g.lastID),
+		Metric: metric,
+		Labels: labels,
+		Until:  until,
+	}
+
+	g.rules = append(g.rules, rule)
+
+	return rule, nil
+}
+
+// Show removes the rule with the given ID, and returns false if it doesn't
+// exist.
+func (g *Gaps) Show(id string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for i, r := range g.rules {
+		if r.ID == id {
+			g.rules = append(g.rules[:i], g.rules[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Pause pauses the observation of simulated requests until the given time,
+// or until Resume is called if until is zero.
+func (g *Gaps) Pause(until time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.paused = true
+	g.pausedUntil = until
+}
+
+// Resume resumes the observation of simulated requests.
+func (g *Gaps) Resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.paused = false
+	g.pausedUntil = time.Time{}
+}
+
+// Paused returns whether the observation of simulated requests is paused at
+// time now.
+func (g *Gaps) Paused(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.isPaused(now)
+}
+
+func (g *Gaps) isPaused(now time.Time) bool {
+	if g.paused && !g.pausedUntil.IsZero() && !now.Before(g.pausedUntil) {
+		g.paused = false
+		g.pausedUntil = time.Time{}
+	}
+
+	return g.paused
+}
+
+// State returns the state at time now.
+func (g *Gaps) State(now time.Time) State {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	state := State{
+		Paused: g.isPaused(now),
+		Hidden: g.activeRules(now),
+	}
+
+	if state.Paused {
+		state.PausedUntil = g.pausedUntil
+	}
+
+	return state
+}
+
+func (g *Gaps) activeRules(now time.Time) []Rule {
+	n := 0
+
+	for _, r := range g.rules {
+		if !r.expired(now) {
+			g.rules[n] = r
+			n++
+		}
+	}
+
+	g.rules = g.rules[:n]
+
+	return append([]Rule{}, g.rules...)
+}
+
+// Gatherer returns a gatherer that removes the hidden series from the metrics
+// gathered by next.
+func (g *Gaps) Gatherer(next prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := next.Gather()
+
+		g.mu.Lock()
+		rules := g.activeRules(time.Now())
+		g.mu.Unlock()
+
+		if len(rules) == 0 {
+			return families, err
+		}
+
+		return filter(families, rules), err
+	})
+}
+
+func filter(families []*dto.MetricFamily, rules []Rule) []*dto.MetricFamily {
+	var result []*dto.MetricFamily
+
+	for _, family := range families {
+		var metrics []*dto.Metric
+
+		for _, metric := range family.GetMetric() {
+			if !hidden(family, metric, rules) {
+				metrics = append(metrics, metric)
+			}
+		}
+
+		if len(metrics) == 0 {
+			continue
+		}
+
+		if len(metrics) < len(family.GetMetric()) {
+			family = &dto.MetricFamily{
+				Name:   family.Name,
+				Help:   family.Help,
+				Type:   family.Type,
+				Metric: metrics,
+			}
+		}
+
+		result = append(result, family)
+	}
+
+	return result
+}
+
+func hidden(family *dto.MetricFamily, metric *dto.Metric, rules []Rule) bool {
+	for _, r := range rules {
+		if r.matches(family, metric) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,119 @@
+package gaps_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/gaps"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newRegistry(t *testing.T) *prometheus.Registry {
+	t.Helper()
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_total", Help: "Test"}, []string{"code"})
+	counter.WithLabelValues("200").Inc()
+	counter.WithLabelValues("500").Inc()
+
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge", Help: "Test"})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(counter, gauge)
+
+	return registry
+}
+
+func TestHide(t *testing.T) {
+	var g gaps.Gaps
+
+	gatherer := g.Gatherer(newRegistry(t))
+
+	if _, err := g.Hide("test_total", map[string]string{"code": "500"}, time.Time{}); err != nil {
+		t.Fatalf("hide: %v", err)
+	}
+
+	rule, err := g.Hide("test_gauge", nil, time.Time{})
+	if err != nil {
+		t.Fatalf("hide: %v", err)
+	}
+
+	expected := `
+		# HELP test_total Test
+		# TYPE test_total counter
+		test_total{code="200"} 1
+	`
+
+	if err := testutil.GatherAndCompare(gatherer, strings.NewReader(expected)); err != nil {
+		t.Fatalf("invalid metrics: %v", err)
+	}
+
+	if !g.Show(rule.ID) {
+		t.Fatalf("rule not removed")
+	}
+
+	if g.Show(rule.ID) {
+		t.Fatalf("rule removed twice")
+	}
+
+	if n, err := testutil.GatherAndCount(gatherer, "test_gauge"); err != nil || n != 1 {
+		t.Fatalf("gauge not shown: %v, %v", n, err)
+	}
+}
+
+func TestHideExpired(t *testing.T) {
+	var g gaps.Gaps
+
+	if _, err := g.Hide("test_gauge", nil, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("hide: %v", err)
+	}
+
+	if n, err := testutil.GatherAndCount(g.Gatherer(newRegistry(t)), "test_gauge"); err != nil || n != 1 {
+		t.Fatalf("gauge hidden by an expired rule: %v, %v", n, err)
+	}
+
+	if state := g.State(time.Now()); len(state.Hidden) != 0 {
+		t.Fatalf("expired rule listed: %+v", state)
+	}
+}
+
+func TestHideInvalid(t *testing.T) {
+	var g gaps.Gaps
+
+	if _, err := g.Hide("not a metric", nil, time.Time{}); err == nil {
+		t.Fatalf("no error for invalid metric")
+	}
+
+	if _, err := g.Hide("test_total", map[string]string{"not a label": ""}, time.Time{}); err == nil {
+		t.Fatalf("no error for invalid label")
+	}
+}
+
+func TestPause(t *testing.T) {
+	var g gaps.Gaps
+
+	now := time.Unix(1000, 0)
+
+	g.Pause(now.Add(time.Minute))
+
+	if !g.Paused(now) {
+		t.Fatalf("not paused")
+	}
+
+	if g.Paused(now.Add(time.Minute)) {
+		t.Fatalf("pause not expired")
+	}
+
+	g.Pause(time.Time{})
+
+	if !g.Paused(now.Add(time.Hour)) {
+		t.Fatalf("not paused")
+	}
+
+	g.Resume()
+
+	if g.Paused(now) {
+		t.Fatalf("not resumed")
+	}
+}
@@ -0,0 +1,169 @@
+// Package gauges exposes synthetic gauges, whose values are driven by
+// configurable models.
+package gauges
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/stats"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// MetricPrefix is prepended to the name of every gauge to obtain the name of
+// its metric. The gauges have a namespace of their own, so that their names
+// can't clash with the other metrics of the generator.
+const MetricPrefix = "metrics_generator_gauge_"
+
+// DefaultHelp is the help of gauges that don't specify one.
+const DefaultHelp = "Synthetic gauge"
+
+type Traffic interface {
+	Summary(now time.Time, window time.Duration, quantiles []float64) stats.Summary
+}
+
+// Gauge is a synthetic gauge.
+type Gauge struct {
+	Name  string
+	Help  string
+	Model Model
+}
+
+// Value is a gauge together with its current value.
+type Value struct {
+	Gauge
+	Value float64
+}
+
+type gauge struct {
+	Gauge
+	value float64
+	last  time.Time
+}
+
+// Set is a set of synthetic gauges. Its zero value contains no gauges.
+type Set struct {
+	// Traffic, if set, provides the traffic used by TypeTraffic. Otherwise,
+	// gauges of that type are always zero.
+	Traffic Traffic
+
+	// Offset, if set, returns a value added to the gauge with the given
+	// name at time now.
+	Offset func(name string, now time.Time) float64
+
+	mu     sync.Mutex
+	gauges map[string]*gauge
+}
+
+// Put adds a gauge, or replaces the gauge with the same name. A stateful
+// gauge keeps its current value if its model type doesn't change.
+func (s *Set) Put(g Gauge) error {
+	if g.Name == "" || !model.IsValidMetricName(model.LabelValue(MetricPrefix+g.Name)) {
+		return fmt.Errorf("invalid gauge name %q", g.Name)
+	}
+
+	if err := g.Model.Validate(); err != nil {
+		return err
+	}
+
+	if g.Help == "" {
+		g.Help = DefaultHelp
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.gauges == nil {
+		s.gauges = make(map[string]*gauge)
+	}
+
+	if old, ok := s.gauges[g.Name]; ok && old.Model.Type == g.Model.Type {
+		old.Gauge = g
+		return nil
+	}
+
+	s.gauges[g.Name] = &gauge{Gauge: g}
+
+	return nil
+}
+
+// Delete removes a gauge, and returns false if it doesn't exist.
+func (s *Set) Delete(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.gauges[name]; !ok {
+		return false
+	}
+
+	delete(s.gauges, name)
+
+	return true
+}
+
+// List returns the gauges and their value at time now, sorted by name.
+func (s *Set) List(now time.Time) []Value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := make([]Value, 0, len(s.gauges))
+
+	for _, g := range s.gauges {
+		values = append(values, Value{
+			Gauge: g.Gauge,
+			Value: s.value(g, now),
+		})
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Name < values[j].Name
+	})
+
+	return values
+}
+
+func (s *Set) value(g *gauge, now time.Time) float64 {
+	var value float64
+
+	switch g.Model.Type {
+	case TypeSawtooth, TypeSine:
+		value = g.Model.periodic(now)
+	case TypeTraffic:
+		if s.Traffic != nil {
+			summary := s.Traffic.Summary(now, g.Model.window(), nil)
+			value = summary.Rate * summary.Mean
+		}
+	default:
+		if g.last.IsZero() {
+			g.value = g.Model.initial()
+		} else if now.After(g.last) {
+			g.value = g.Model.next(g.value, now.Sub(g.last).Seconds())
+		}
+
+		if now.After(g.last) {
+			g.last = now
+		}
+
+		value = g.value
+	}
+
+	if s.Offset != nil {
+		value += s.Offset(g.Name, now)
+	}
+
+	return value
+}
+
+// Describe sends no descriptors, because gauges can be added and removed at
+// any time.
+func (s *Set) Describe(ch chan<- *prometheus.Desc) {}
+
+func (s *Set) Collect(ch chan<- prometheus.Metric) {
+	for _, v := range s.List(time.Now()) {
+		desc := prometheus.NewDesc(MetricPrefix+v.Name, v.Help, nil, nil)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v.Value)
+	}
+}
@@ -0,0 +1,206 @@
+package gauges_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/gauges"
+	"github.com/francescomari/metrics-generator/internal/stats"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type traffic stats.Summary
+
+func (t traffic) Summary(time.Time, time.Duration, []float64) stats.Summary {
+	return stats.Summary(t)
+}
+
+func value(t *testing.T, set *gauges.Set, name string, now time.Time) float64 {
+	t.Helper()
+
+	for _, v := range set.List(now) {
+		if v.Name == name {
+			return v.Value
+		}
+	}
+
+	t.Fatalf("gauge %s not found", name)
+
+	return 0
+}
+
+func TestPeriodic(t *testing.T) {
+	var set gauges.Set
+
+	put := []gauges.Gauge{
+		{Name: "sawtooth", Model: gauges.Model{Type: gauges.TypeSawtooth, Min: 10, Max: 20, Period: time.Minute}},
+		{Name: "sine", Model: gauges.Model{Type: gauges.TypeSine, Min: 10, Max: 20, Period: time.Minute}},
+	}
+
+	for _, g := range put {
+		if err := set.Put(g); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+
+	start := time.Unix(600, 0)
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		value   float64
+	}{
+		{"sawtooth", 0, 10},
+		{"sawtooth", 30 * time.Second, 15},
+		{"sawtooth", time.Minute, 10},
+		{"sine", 0, 15},
+		{"sine", 15 * time.Second, 20},
+		{"sine", 45 * time.Second, 10},
+	}
+
+	for _, test := range tests {
+		if v := value(t, &set, test.name, start.Add(test.elapsed)); math.Abs(v-test.value) > 1e-9 {
+			t.Errorf("invalid value of %s after %v: %v", test.name, test.elapsed, v)
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	var set gauges.Set
+
+	put := []gauges.Gauge{
+		{Name: "walk", Model: gauges.Model{Type: gauges.TypeRandomWalk, Min: 0, Max: 10, Volatility: 5}},
+		{Name: "reverting", Model: gauges.Model{Type: gauges.TypeMeanReverting, Min: 0, Max: 1, Mean: 0.5, Reversion: 1, Volatility: 0.5}},
+	}
+
+	for _, g := range put {
+		if err := set.Put(g); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+
+	now := time.Unix(1000, 0)
+
+	if v := value(t, &set, "walk", now); v != 5 {
+		t.Fatalf("invalid initial value: %v", v)
+	}
+
+	if v := value(t, &set, "reverting", now); v != 0.5 {
+		t.Fatalf("invalid initial value: %v", v)
+	}
+
+	for i := 0; i < 100; i++ {
+		now = now.Add(time.Second)
+
+		if v := value(t, &set, "walk", now); v < 0 || v > 10 {
+			t.Fatalf("value out of bounds: %v", v)
+		}
+
+		if v := value(t, &set, "reverting", now); v < 0 || v > 1 {
+			t.Fatalf("value out of bounds: %v", v)
+		}
+	}
+}
+
+func TestTraffic(t *testing.T) {
+	set := gauges.Set{
+		Traffic: traffic{Rate: 2, Mean: 3},
+		Offset: func(name string, now time.Time) float64 {
+			return 1
+		},
+	}
+
+	if err := set.Put(gauges.Gauge{Name: "in_flight", Model: gauges.Model{Type: gauges.TypeTraffic}}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	if v := value(t, &set, "in_flight", time.Now()); v != 7 {
+		t.Fatalf("invalid value: %v", v)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	var set gauges.Set
+
+	if err := set.Put(gauges.Gauge{Name: "test", Help: "Test", Model: gauges.Model{Type: gauges.TypeTraffic}}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&set)
+
+	expected := `
+		# HELP metrics_generator_gauge_test Test
+		# TYPE metrics_generator_gauge_test gauge
+		metrics_generator_gauge_test 0
+	`
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Fatalf("invalid metrics: %v", err)
+	}
+
+	if !set.Delete("test") {
+		t.Fatalf("gauge not deleted")
+	}
+
+	if n, err := testutil.GatherAndCount(registry); err != nil || n != 0 {
+		t.Fatalf("gauge still collected: %v, %v", n, err)
+	}
+}
+
+func TestCollectNoClash(t *testing.T) {
+	var set gauges.Set
+
+	for _, name := range []string{"request_duration_seconds", "user_requests_total", "config_errors_percentage"} {
+		if err := set.Put(gauges.Gauge{Name: name, Model: gauges.Model{Type: gauges.TypeTraffic}}); err != nil {
+			t.Fatalf("put %s: %v", name, err)
+		}
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&set)
+
+	registry.MustRegister(prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "metrics_generator_request_duration_seconds",
+		Help: "Duration of the simulated requests",
+	}))
+
+	registry.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "metrics_generator_user_requests_total",
+		Help: "Simulated requests by user",
+	}))
+
+	registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "metrics_generator_config_errors_percentage",
+		Help: "Configured percentage of failed requests",
+	}))
+
+	// A gauge with the name of another metric would fail the whole gather.
+	if n, err := testutil.GatherAndCount(registry); err != nil || n != 6 {
+		t.Fatalf("invalid metrics: %v, %v", n, err)
+	}
+}
+
+func TestPutInvalid(t *testing.T) {
+	var set gauges.Set
+
+	invalid := []gauges.Gauge{
+		{Name: "", Model: gauges.Model{Type: gauges.TypeTraffic}},
+		{Name: "not a name", Model: gauges.Model{Type: gauges.TypeTraffic}},
+		{Name: "test", Model: gauges.Model{Type: "unknown"}},
+		{Name: "test", Model: gauges.Model{Type: gauges.TypeSine, Min: 0, Max: 1}},
+		{Name: "test", Model: gauges.Model{Type: gauges.TypeSawtooth, Min: 1, Max: 0, Period: time.Minute}},
+		{Name: "test", Model: gauges.Model{Type: gauges.TypeRandomWalk, Min: 0, Max: 1}},
+		{Name: "test", Model: gauges.Model{Type: gauges.TypeMeanReverting, Volatility: 1}},
+		{Name: "test", Model: gauges.Model{Type: gauges.TypeMeanReverting, Min: 0, Max: 1, Mean: 2, Reversion: 1}},
+	}
+
+	for _, g := range invalid {
+		if err := set.Put(g); err == nil {
+			t.Errorf("no error returned for %+v", g)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package gauges
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Model types.
+const (
+	// TypeRandomWalk moves the value by a random step at every update,
+	// between Min and Max.
+	TypeRandomWalk = "random-walk"
+
+	// TypeMeanReverting moves the value randomly, while pulling it towards
+	// Mean with strength Reversion, like an Ornstein-Uhlenbeck process.
+	TypeMeanReverting = "mean-reverting"
+
+	// TypeSawtooth grows linearly from Min to Max in every period, then
+	// drops back to Min.
+	TypeSawtooth = "sawtooth"
+
+	// TypeSine oscillates between Min and Max with the given period.
+	TypeSine = "sine"
+
+	// TypeTraffic derives the value from the simulated traffic with Little's
+	// law, as the rate of the requests times their mean duration in the last
+	// Window. This is the average number of requests in flight.
+	TypeTraffic = "traffic"
+)
+
+// Types are the supported model types.
+var Types = []string{TypeRandomWalk, TypeMeanReverting, TypeSawtooth, TypeSine, TypeTraffic}
+
+// DefaultWindow is the default window of TypeTraffic.
+const DefaultWindow = time.Minute
+
+// Model describes how the value of a gauge changes over time. The fields
+// used depend on the type.
+type Model struct {
+	Type string
+
+	// Min and Max bound the value. They are required by TypeRandomWalk,
+	// TypeSawtooth and TypeSine, and optional for TypeMeanReverting.
+	Min float64
+	Max float64
+
+	// Period is the period of TypeSawtooth and TypeSine.
+	Period time.Duration
+
+	// Volatility is the standard deviation of the change of the value in
+	// one second, for TypeRandomWalk and TypeMeanReverting.
+	Volatility float64
+
+	// Mean and Reversion are the long-term mean of TypeMeanReverting and how
+	// fast the value is pulled towards it, per second.
+	Mean      float64
+	Reversion float64
+
+	// Window is the window over which TypeTraffic looks at the traffic. If
+	// zero, DefaultWindow is used.
+	Window time.Duration
+}
+
+// Validate returns an error if the model is invalid.
+func (m Model) Validate() error {
+	switch m.Type {
+	case TypeRandomWalk:
+		if m.Volatility <= 0 {
+			return fmt.Errorf("volatility must be positive")
+		}
+
+		if m.Max <= m.Min {
+			return fmt.Errorf("maximum must be greater than minimum")
+		}
+	case TypeMeanReverting:
+		if m.Volatility < 0 {
+			return fmt.Errorf("volatility must not be negative")
+		}
+
+		if m.Reversion <= 0 {
+			return fmt.Errorf("reversion must be positive")
+		}
+
+		if m.bounded() && (m.Mean < m.Min || m.Mean > m.Max) {
+			return fmt.Errorf("mean must be between minimum and maximum")
+		}
+
+		if m.Max < m.Min {
+			return fmt.Errorf("maximum must not be less than minimum")
+		}
+	case TypeSawtooth, TypeSine:
+		if m.Period <= 0 {
+			return fmt.Errorf("period must be positive")
+		}
+
+		if m.Max <= m.Min {
+			return fmt.Errorf("maximum must be greater than minimum")
+		}
+	case TypeTraffic:
+		if m.Window < 0 {
+			return fmt.Errorf("window must not be negative")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", m.Type)
+	}
+
+	return nil
+}
+
+func (m Model) bounded() bool {
+	return m.Min != 0 || m.Max != 0
+}
+
+func (m Model) window() time.Duration {
+	if m.Window > 0 {
+		return m.Window
+	}
+
+	return DefaultWindow
+}
+
+// initial returns the value of a stateful model when it starts.
+func (m Model) initial() float64 {
+	if m.Type == TypeMeanReverting {
+		return m.Mean
+	}
+
+	return (m.Min + m.Max) / 2
+}
+
+// next returns the value of a stateful model dt seconds after it had the
+// given value.
+func (m Model) next(value, dt float64) float64 {
+	switch m.Type {
+	case TypeRandomWalk:
+		value += m.Volatility * math.Sqrt(dt) * rand.NormFloat64()
+	case TypeMeanReverting:
+		// Exact transition of the Ornstein-Uhlenbeck process, which stays
+		// stable however long it has been since the last update.
+		decay := math.Exp(-m.Reversion * dt)
+		stddev := m.Volatility * math.Sqrt((1-decay*decay)/(2*m.Reversion))
+		value = m.Mean + (value-m.Mean)*decay + stddev*rand.NormFloat64()
+	}
+
+	if m.bounded() {
+		value = math.Max(m.Min, math.Min(m.Max, value))
+	}
+
+	return value
+}
+
+// periodic returns the value of a periodic model at time now.
+func (m Model) periodic(now time.Time) float64 {
+	phase := float64(now.UnixNano()%int64(m.Period)) / float64(m.Period)
+
+	if m.Type == TypeSawtooth {
+		return m.Min + (m.Max-m.Min)*phase
+	}
+
+	return m.Min + (m.Max-m.Min)*(1+math.Sin(2*math.Pi*phase))/2
+}
@@ -0,0 +1,290 @@
+// Package grpcapi implements the gRPC control-plane service defined in
+// package controlpb.
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/francescomari/metrics-generator/internal/auth"
+	"github.com/francescomari/metrics-generator/internal/events"
+	"github.com/francescomari/metrics-generator/pkg/controlpb"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+type Config interface {
+	DurationInterval() (int, int)
+	SetDurationInterval(min, max int) error
+	ErrorsPercentage() float64
+	SetErrorsPercentage(value float64) error
+	RequestsHour() int
+	SetRequestsHour(reqHour int) error
+}
+
+type EventSource interface {
+	Subscribe() *events.Subscription
+	Unsubscribe(s *events.Subscription)
+}
+
+type Authenticator interface {
+	AuthenticateHeader(header string) (auth.Role, error)
+}
+
+type Counter interface {
+	Inc()
+}
+
+// Server implements controlpb.ControlServer on top of the configuration of
+// the generator. Values are validated by Config, and rejected values are
+// reported with codes.InvalidArgument. Values set through other APIs that
+// don't fit the int32 fields of the messages are reported with
+// codes.OutOfRange instead of being truncated.
+type Server struct {
+	controlpb.UnimplementedControlServer
+
+	Config Config
+
+	// Events provides the configuration changes streamed by WatchConfig.
+	Events EventSource
+
+	// Authenticator, if set, protects the methods that change the
+	// configuration. Credentials are read from the "authorization" metadata,
+	// in the same format as the HTTP Authorization header.
+	Authenticator Authenticator
+
+	// Unauthorized, if set, counts the calls rejected by the Authenticator.
+	Unauthorized Counter
+
+	// Logger, if set, receives the rejected calls.
+	Logger log.Logger
+}
+
+// NewGRPCServer returns a gRPC server serving the Control service and the
+// reflection service.
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	if s.Authenticator != nil {
+		opts = append(opts, grpc.UnaryInterceptor(s.requireAdminForChanges))
+	}
+
+	server := grpc.NewServer(opts...)
+
+	controlpb.RegisterControlServer(server, s)
+	reflection.Register(server)
+
+	return server
+}
+
+func (s *Server) requireAdminForChanges(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !isChange(info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	var header string
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			header = values[0]
+		}
+	}
+
+	role, err := s.Authenticator.AuthenticateHeader(header)
+	if err != nil {
+		s.reject(info.FullMethod, "invalid credentials: %v", err)
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+
+	switch role {
+	case auth.RoleNone:
+		s.reject(info.FullMethod, "missing credentials")
+		return nil, status.Error(codes.Unauthenticated, "missing credentials")
+	case auth.RoleAdmin:
+		return handler(ctx, req)
+	default:
+		s.reject(info.FullMethod, "insufficient role %v", role)
+		return nil, status.Error(codes.PermissionDenied, "insufficient permissions")
+	}
+}
+
+func (s *Server) reject(method, format string, args ...interface{}) {
+	if s.Logger != nil {
+		level.Warn(s.Logger).Log("msg", "Rejected unauthorized gRPC call", "method", method, "reason", fmt.Sprintf(format, args...))
+	}
+
+	if s.Unauthorized != nil {
+		s.Unauthorized.Inc()
+	}
+}
+
+// isChange returns whether the method changes the configuration.
+func isChange(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod[strings.LastIndex(fullMethod, "/")+1:], "Set")
+}
+
+func (s *Server) GetConfig(ctx context.Context, req *controlpb.GetConfigRequest) (*controlpb.Config, error) {
+	return s.config()
+}
+
+func (s *Server) GetDurationInterval(ctx context.Context, req *controlpb.GetDurationIntervalRequest) (*controlpb.DurationInterval, error) {
+	return s.durationInterval()
+}
+
+func (s *Server) SetDurationInterval(ctx context.Context, req *controlpb.SetDurationIntervalRequest) (*controlpb.DurationInterval, error) {
+	if err := s.Config.SetDurationInterval(int(req.Min), int(req.Max)); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "set duration interval: %v", err)
+	}
+
+	return s.durationInterval()
+}
+
+func (s *Server) GetErrorsPercentage(ctx context.Context, req *controlpb.GetErrorsPercentageRequest) (*controlpb.ErrorsPercentage, error) {
+	return &controlpb.ErrorsPercentage{Value: s.Config.ErrorsPercentage()}, nil
+}
+
+func (s *Server) SetErrorsPercentage(ctx context.Context, req *controlpb.SetErrorsPercentageRequest) (*controlpb.ErrorsPercentage, error) {
+	if err := s.Config.SetErrorsPercentage(req.Value); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "set errors percentage: %v", err)
+	}
+
+	return &controlpb.ErrorsPercentage{Value: s.Config.ErrorsPercentage()}, nil
+}
+
+func (s *Server) GetRequestsHour(ctx context.Context, req *controlpb.GetRequestsHourRequest) (*controlpb.RequestsHour, error) {
+	return s.requestsHour()
+}
+
+func (s *Server) SetRequestsHour(ctx context.Context, req *controlpb.SetRequestsHourRequest) (*controlpb.RequestsHour, error) {
+	if err := s.Config.SetRequestsHour(int(req.Value)); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "set requests per hour: %v", err)
+	}
+
+	return s.requestsHour()
+}
+
+// WatchConfig sends the current configuration, and then the configuration
+// carried by every config event. If events were dropped, the current
+// configuration is sent again, so that the client never misses the latest
+// change.
+func (s *Server) WatchConfig(req *controlpb.WatchConfigRequest, stream controlpb.Control_WatchConfigServer) error {
+	if s.Events == nil {
+		return status.Error(codes.Unimplemented, "watching the configuration is not supported")
+	}
+
+	subscription := s.Events.Subscribe()
+	defer s.Events.Unsubscribe(subscription)
+
+	if err := s.sendConfig(stream); err != nil {
+		return err
+	}
+
+	var dropped uint64
+
+	for {
+		select {
+		case e := <-subscription.Events():
+			if current := subscription.Dropped(); current != dropped {
+				dropped = current
+
+				if err := s.sendConfig(stream); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if e.Type != events.TypeConfig || e.Config == nil {
+				continue
+			}
+
+			config, err := newConfig(e.Config.MinDuration, e.Config.MaxDuration, e.Config.ErrorsPercentage, e.Config.RequestsHour)
+			if err != nil {
+				return err
+			}
+
+			if err := stream.Send(config); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (s *Server) sendConfig(stream controlpb.Control_WatchConfigServer) error {
+	config, err := s.config()
+	if err != nil {
+		return err
+	}
+
+	return stream.Send(config)
+}
+
+func (s *Server) config() (*controlpb.Config, error) {
+	min, max := s.Config.DurationInterval()
+
+	return newConfig(min, max, s.Config.ErrorsPercentage(), s.Config.RequestsHour())
+}
+
+func (s *Server) durationInterval() (*controlpb.DurationInterval, error) {
+	return newDurationInterval(s.Config.DurationInterval())
+}
+
+func (s *Server) requestsHour() (*controlpb.RequestsHour, error) {
+	value, err := toInt32("requests per hour", s.Config.RequestsHour())
+	if err != nil {
+		return nil, err
+	}
+
+	return &controlpb.RequestsHour{Value: value}, nil
+}
+
+func newConfig(min, max int, errorsPercentage float64, requestsHour int) (*controlpb.Config, error) {
+	interval, err := newDurationInterval(min, max)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := toInt32("requests per hour", requestsHour)
+	if err != nil {
+		return nil, err
+	}
+
+	config := controlpb.Config{
+		DurationInterval: interval,
+		ErrorsPercentage: errorsPercentage,
+		RequestsHour:     value,
+	}
+
+	return &config, nil
+}
+
+func newDurationInterval(min, max int) (*controlpb.DurationInterval, error) {
+	minValue, err := toInt32("minimum duration", min)
+	if err != nil {
+		return nil, err
+	}
+
+	maxValue, err := toInt32("maximum duration", max)
+	if err != nil {
+		return nil, err
+	}
+
+	return &controlpb.DurationInterval{Min: minValue, Max: maxValue}, nil
+}
+
+// toInt32 converts a value of the configuration to the type of the fields of
+// the messages. The values received in the messages always fit an int.
+func toInt32(name string, value int) (int32, error) {
+	if value < math.MinInt32 || value > math.MaxInt32 {
+		return 0, status.Errorf(codes.OutOfRange, "%s %d doesn't fit in a 32-bit integer", name, value)
+	}
+
+	return int32(value), nil
+}
@@ -0,0 +1,254 @@
+package grpcapi_test
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/auth"
+	"github.com/francescomari/metrics-generator/internal/events"
+	"github.com/francescomari/metrics-generator/internal/grpcapi"
+	"github.com/francescomari/metrics-generator/internal/limits"
+	"github.com/francescomari/metrics-generator/pkg/controlpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newConfig(t *testing.T) *limits.Config {
+	t.Helper()
+
+	var config limits.Config
+
+	if err := config.SetDurationInterval(1, 10); err != nil {
+		t.Fatalf("set duration interval: %v", err)
+	}
+
+	if err := config.SetErrorsPercentage(10); err != nil {
+		t.Fatalf("set errors percentage: %v", err)
+	}
+
+	if err := config.SetRequestsHour(1000); err != nil {
+		t.Fatalf("set requests hour: %v", err)
+	}
+
+	return &config
+}
+
+func newClient(t *testing.T, server *grpcapi.Server) controlpb.ControlClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+
+	grpcServer := server.NewGRPCServer()
+
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}
+
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return controlpb.NewControlClient(conn)
+}
+
+func TestGetAndSet(t *testing.T) {
+	config := newConfig(t)
+	client := newClient(t, &grpcapi.Server{Config: config})
+
+	ctx := context.Background()
+
+	interval, err := client.SetDurationInterval(ctx, &controlpb.SetDurationIntervalRequest{Min: 15, Max: 45})
+	if err != nil {
+		t.Fatalf("set duration interval: %v", err)
+	}
+
+	if interval.Min != 15 || interval.Max != 45 {
+		t.Fatalf("invalid duration interval: %v", interval)
+	}
+
+	if _, err := client.SetErrorsPercentage(ctx, &controlpb.SetErrorsPercentageRequest{Value: 25.5}); err != nil {
+		t.Fatalf("set errors percentage: %v", err)
+	}
+
+	if _, err := client.SetRequestsHour(ctx, &controlpb.SetRequestsHourRequest{Value: 2000}); err != nil {
+		t.Fatalf("set requests hour: %v", err)
+	}
+
+	got, err := client.GetConfig(ctx, &controlpb.GetConfigRequest{})
+	if err != nil {
+		t.Fatalf("get config: %v", err)
+	}
+
+	if got.DurationInterval.Min != 15 || got.DurationInterval.Max != 45 || got.ErrorsPercentage != 25.5 || got.RequestsHour != 2000 {
+		t.Fatalf("invalid config: %v", got)
+	}
+
+	if min, max := config.DurationInterval(); min != 15 || max != 45 {
+		t.Fatalf("config not changed: %d,%d", min, max)
+	}
+}
+
+func TestInvalidArgument(t *testing.T) {
+	client := newClient(t, &grpcapi.Server{Config: newConfig(t)})
+
+	ctx := context.Background()
+
+	_, err := client.SetErrorsPercentage(ctx, &controlpb.SetErrorsPercentageRequest{Value: 200})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid error: %v", err)
+	}
+
+	_, err = client.SetDurationInterval(ctx, &controlpb.SetDurationIntervalRequest{Min: 10, Max: 1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid error: %v", err)
+	}
+}
+
+func TestOutOfRange(t *testing.T) {
+	if strconv.IntSize == 32 {
+		t.Skip("every int fits in an int32")
+	}
+
+	tooLarge := math.MaxInt32
+	tooLarge++
+
+	config := newConfig(t)
+
+	if err := config.SetRequestsHour(tooLarge); err != nil {
+		t.Fatalf("set requests hour: %v", err)
+	}
+
+	if err := config.SetDurationInterval(1, tooLarge); err != nil {
+		t.Fatalf("set duration interval: %v", err)
+	}
+
+	client := newClient(t, &grpcapi.Server{Config: config})
+
+	ctx := context.Background()
+
+	if _, err := client.GetConfig(ctx, &controlpb.GetConfigRequest{}); status.Code(err) != codes.OutOfRange {
+		t.Fatalf("invalid error for config: %v", err)
+	}
+
+	if _, err := client.GetRequestsHour(ctx, &controlpb.GetRequestsHourRequest{}); status.Code(err) != codes.OutOfRange {
+		t.Fatalf("invalid error for requests per hour: %v", err)
+	}
+
+	if _, err := client.GetDurationInterval(ctx, &controlpb.GetDurationIntervalRequest{}); status.Code(err) != codes.OutOfRange {
+		t.Fatalf("invalid error for duration interval: %v", err)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	credentials, err := auth.Parse([]byte("tokens:\n  - token: admin\n    role: admin\n  - token: reader\n    role: read-only\n"))
+	if err != nil {
+		t.Fatalf("parse credentials: %v", err)
+	}
+
+	client := newClient(t, &grpcapi.Server{
+		Config:        newConfig(t),
+		Authenticator: credentials,
+	})
+
+	ctx := context.Background()
+
+	if _, err := client.GetRequestsHour(ctx, &controlpb.GetRequestsHourRequest{}); err != nil {
+		t.Fatalf("get requests hour: %v", err)
+	}
+
+	tests := []struct {
+		header string
+		code   codes.Code
+	}{
+		{"", codes.Unauthenticated},
+		{"Bearer boom", codes.Unauthenticated},
+		{"Bearer reader", codes.PermissionDenied},
+		{"Bearer admin", codes.OK},
+	}
+
+	for _, test := range tests {
+		ctx := ctx
+
+		if test.header != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", test.header)
+		}
+
+		_, err := client.SetRequestsHour(ctx, &controlpb.SetRequestsHourRequest{Value: 10})
+
+		if code := status.Code(err); code != test.code {
+			t.Errorf("invalid code for %q: got %v, want %v", test.header, code, test.code)
+		}
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	config := newConfig(t)
+
+	var broker events.Broker
+
+	config.Subscribe(func() {
+		min, max := config.DurationInterval()
+
+		broker.Publish(events.Event{
+			Type: events.TypeConfig,
+			Config: &events.Config{
+				MinDuration:      min,
+				MaxDuration:      max,
+				ErrorsPercentage: config.ErrorsPercentage(),
+				RequestsHour:     config.RequestsHour(),
+			},
+		})
+	})
+
+	client := newClient(t, &grpcapi.Server{
+		Config: config,
+		Events: &broker,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchConfig(ctx, &controlpb.WatchConfigRequest{})
+	if err != nil {
+		t.Fatalf("watch config: %v", err)
+	}
+
+	initial, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("receive initial config: %v", err)
+	}
+
+	if initial.RequestsHour != 1000 {
+		t.Fatalf("invalid initial config: %v", initial)
+	}
+
+	// Publish a request event, which must not be streamed.
+	broker.Publish(events.Event{Type: events.TypeRequest, Request: &events.Request{Duration: 1}})
+
+	if err := config.SetRequestsHour(3600); err != nil {
+		t.Fatalf("set requests hour: %v", err)
+	}
+
+	changed, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("receive changed config: %v", err)
+	}
+
+	if changed.RequestsHour != 3600 {
+		t.Fatalf("invalid changed config: %v", changed)
+	}
+}
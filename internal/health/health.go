@@ -0,0 +1,188 @@
+// Package health tracks the progress of the generator and decides the outcome
+// of the readiness and liveness probes.
+package health
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Probe names.
+const (
+	ProbeReady = "ready"
+	ProbeLive  = "live"
+)
+
+const (
+	// DefaultReadyMaxLag is the default lag after which the readiness probe
+	// fails.
+	DefaultReadyMaxLag = 5 * time.Second
+
+	// DefaultLiveMaxLag is the default lag after which the liveness probe
+	// fails.
+	DefaultLiveMaxLag = 30 * time.Second
+)
+
+// Result is the outcome of a probe.
+type Result struct {
+	Probe   string `json:"probe"`
+	Healthy bool   `json:"healthy"`
+
+	// Reason explains why the probe failed.
+	Reason string `json:"reason,omitempty"`
+
+	// LastTick is the time of the last iteration of the generator, zero if
+	// the generator hasn't run yet.
+	LastTick time.Time `json:"lastTick"`
+
+	// NextTick is when the next iteration of the generator is expected.
+	NextTick time.Time `json:"nextTick"`
+
+	// Lag is how late the generator is compared to its schedule, in
+	// seconds.
+	Lag float64 `json:"lag"`
+
+	// MaxLag is the lag, in seconds, after which the probe fails.
+	MaxLag float64 `json:"maxLag"`
+
+	// Forced is true if the probe was forced to fail through the API.
+	Forced bool `json:"forced"`
+
+	// ForcedUntil is when the forced failure ends, nil if it lasts until it
+	// is cleared.
+	ForcedUntil *time.Time `json:"forcedUntil,omitempty"`
+}
+
+// Checker receives a heartbeat from the generator at every iteration, and
+// checks the probes against it. A probe fails if the generator never ran, if
+// it is late by more than the maximum lag of the probe, or if the probe was
+// forced to fail.
+type Checker struct {
+	// ReadyMaxLag is the lag after which the readiness probe fails. If zero,
+	// DefaultReadyMaxLag is used.
+	ReadyMaxLag time.Duration
+
+	// LiveMaxLag is the lag after which the liveness probe fails. If zero,
+	// DefaultLiveMaxLag is used.
+	LiveMaxLag time.Duration
+
+	mu       sync.Mutex
+	lastTick time.Time
+	nextTick time.Time
+	forced   map[string]time.Time
+}
+
+// Beat records an iteration of the generator at time now, after which the
+// generator sleeps for the given duration.
+func (c *Checker) Beat(now time.Time, sleep time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastTick = now
+	c.nextTick = now.Add(sleep)
+}
+
+// Fail forces the probe to fail until the given time, or until Clear is
+// called if until is zero.
+func (c *Checker) Fail(probe string, until time.Time) error {
+	if err := checkProbe(probe); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.forced == nil {
+		c.forced = make(map[string]time.Time)
+	}
+
+	c.forced[probe] = until
+
+	return nil
+}
+
+// Clear removes a forced failure of the probe.
+func (c *Checker) Clear(probe string) error {
+	if err := checkProbe(probe); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.forced, probe)
+
+	return nil
+}
+
+// Check returns the outcome of the probe at time now.
+func (c *Checker) Check(probe string, now time.Time) (Result, error) {
+	if err := checkProbe(probe); err != nil {
+		return Result{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := Result{
+		Probe:    probe,
+		Healthy:  true,
+		LastTick: c.lastTick,
+		NextTick: c.nextTick,
+		MaxLag:   c.maxLag(probe).Seconds(),
+	}
+
+	if !c.nextTick.IsZero() && now.After(c.nextTick) {
+		result.Lag = now.Sub(c.nextTick).Seconds()
+	}
+
+	if until, ok := c.forced[probe]; ok {
+		if until.IsZero() || now.Before(until) {
+			result.Forced = true
+
+			if !until.IsZero() {
+				result.ForcedUntil = &until
+			}
+		} else {
+			delete(c.forced, probe)
+		}
+	}
+
+	switch {
+	case result.Forced:
+		result.Healthy = false
+		result.Reason = "forced to fail"
+	case c.lastTick.IsZero():
+		result.Healthy = false
+		result.Reason = "generator not started"
+	case result.Lag > result.MaxLag:
+		result.Healthy = false
+		result.Reason = fmt.Sprintf("generator late by %.3fs", result.Lag)
+	}
+
+	return result, nil
+}
+
+func (c *Checker) maxLag(probe string) time.Duration {
+	if probe == ProbeReady {
+		if c.ReadyMaxLag > 0 {
+			return c.ReadyMaxLag
+		}
+		return DefaultReadyMaxLag
+	}
+
+	if c.LiveMaxLag > 0 {
+		return c.LiveMaxLag
+	}
+
+	return DefaultLiveMaxLag
+}
+
+func checkProbe(probe string) error {
+	if probe != ProbeReady && probe != ProbeLive {
+		return fmt.Errorf("unknown probe %q", probe)
+	}
+
+	return nil
+}
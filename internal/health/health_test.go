@@ -0,0 +1,104 @@
+package health_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/health"
+)
+
+func TestCheckNotStarted(t *testing.T) {
+	var c health.Checker
+
+	result, err := c.Check(health.ProbeReady, time.Now())
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+
+	if result.Healthy {
+		t.Fatalf("probe is healthy before the first heartbeat")
+	}
+}
+
+func TestCheckLag(t *testing.T) {
+	c := health.Checker{
+		ReadyMaxLag: 5 * time.Second,
+		LiveMaxLag:  30 * time.Second,
+	}
+
+	now := time.Unix(1000, 0)
+
+	c.Beat(now, time.Minute)
+
+	tests := []struct {
+		probe   string
+		elapsed time.Duration
+		lag     float64
+		healthy bool
+	}{
+		{health.ProbeReady, 30 * time.Second, 0, true},
+		{health.ProbeReady, time.Minute + 5*time.Second, 5, true},
+		{health.ProbeReady, time.Minute + 6*time.Second, 6, false},
+		{health.ProbeLive, time.Minute + 6*time.Second, 6, true},
+		{health.ProbeLive, time.Minute + 31*time.Second, 31, false},
+	}
+
+	for _, test := range tests {
+		result, err := c.Check(test.probe, now.Add(test.elapsed))
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+
+		if result.Lag != test.lag || result.Healthy != test.healthy {
+			t.Errorf("invalid result for %s after %v: %+v", test.probe, test.elapsed, result)
+		}
+	}
+}
+
+func TestFail(t *testing.T) {
+	var c health.Checker
+
+	now := time.Unix(1000, 0)
+
+	c.Beat(now, time.Second)
+
+	if err := c.Fail(health.ProbeLive, now.Add(time.Minute)); err != nil {
+		t.Fatalf("fail: %v", err)
+	}
+
+	if result, _ := c.Check(health.ProbeLive, now); result.Healthy || !result.Forced {
+		t.Fatalf("probe not forced to fail: %+v", result)
+	}
+
+	if result, _ := c.Check(health.ProbeReady, now); !result.Healthy {
+		t.Fatalf("other probe forced to fail: %+v", result)
+	}
+
+	if result, _ := c.Check(health.ProbeLive, now.Add(time.Minute)); result.Forced {
+		t.Fatalf("forced failure not expired: %+v", result)
+	}
+
+	if err := c.Fail(health.ProbeReady, time.Time{}); err != nil {
+		t.Fatalf("fail: %v", err)
+	}
+
+	if err := c.Clear(health.ProbeReady); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+
+	if result, _ := c.Check(health.ProbeReady, now); !result.Healthy {
+		t.Fatalf("forced failure not cleared: %+v", result)
+	}
+}
+
+func TestUnknownProbe(t *testing.T) {
+	var c health.Checker
+
+	if _, err := c.Check("boom", time.Now()); err == nil {
+		t.Fatalf("no error returned")
+	}
+
+	if err := c.Fail("boom", time.Time{}); err == nil {
+		t.Fatalf("no error returned")
+	}
+}
@@ -13,6 +13,27 @@ type Config struct {
 	errorsPercentage float64
 	sleepDuration    time.Duration
 	reqHour          int
+	listeners        []func()
+}
+
+// Subscribe registers a function to call after every change to the
+// configuration. The function is called synchronously by the goroutine that
+// changed the configuration, and must not block.
+func (c *Config) Subscribe(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.listeners = append(c.listeners, f)
+}
+
+func (c *Config) notify() {
+	c.mu.RLock()
+	listeners := c.listeners
+	c.mu.RUnlock()
+
+	for _, f := range listeners {
+		f()
+	}
 }
 
 func (c *Config) DurationInterval() (int, int) {
@@ -34,15 +55,19 @@ func (c *Config) SetDurationInterval(minDuration, maxDuration int) error {
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	c.minDuration = minDuration
 	c.maxDuration = maxDuration
+	c.mu.Unlock()
+
+	c.notify()
 
 	return nil
 }
 
 func (c *Config) ErrorsPercentage() float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	return c.errorsPercentage
 }
 
@@ -52,18 +77,25 @@ func (c *Config) SetErrorsPercentage(errorsPercentage float64) error {
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	c.errorsPercentage = errorsPercentage
+	c.mu.Unlock()
+
+	c.notify()
 
 	return nil
 }
 
 func (c *Config) RequestsHour() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	return c.reqHour
 }
 
 func (c *Config) SleepDuration() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	return c.sleepDuration
 }
 
@@ -74,11 +106,29 @@ func (c *Config) SetRequestsHour(reqHour int) error {
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	nanos := int64(time.Hour*time.Nanosecond) / int64(reqHour)
 	c.sleepDuration = time.Duration(nanos)
 	c.reqHour = reqHour
+	c.mu.Unlock()
+
+	c.notify()
 
 	return nil
 }
+
+// CopyFrom replaces every value in c with the corresponding value in other. The
+// values are replaced atomically, so readers of c never observe a mix of old
+// and new values. Functions subscribed to other are not copied.
+func (c *Config) CopyFrom(other *Config) {
+	other.mu.RLock()
+	c.mu.Lock()
+	c.minDuration = other.minDuration
+	c.maxDuration = other.maxDuration
+	c.errorsPercentage = other.errorsPercentage
+	c.sleepDuration = other.sleepDuration
+	c.reqHour = other.reqHour
+	c.mu.Unlock()
+	other.mu.RUnlock()
+
+	c.notify()
+}
@@ -33,3 +33,59 @@ func TestSetSleepDuration(t *testing.T) {
 	})
 
 }
+
+func TestCopyFrom(t *testing.T) {
+	var src Config
+
+	if err := src.SetDurationInterval(2, 4); err != nil {
+		t.Fatalf("set duration interval: %v", err)
+	}
+	if err := src.SetErrorsPercentage(12.5); err != nil {
+		t.Fatalf("set errors percentage: %v", err)
+	}
+	if err := src.SetRequestsHour(3600); err != nil {
+		t.Fatalf("set requests hour: %v", err)
+	}
+
+	var dst Config
+
+	dst.CopyFrom(&src)
+
+	if min, max := dst.DurationInterval(); min != 2 || max != 4 {
+		t.Errorf("unexpected duration interval, got %d,%d, want 2,4", min, max)
+	}
+	if got := dst.ErrorsPercentage(); got != 12.5 {
+		t.Errorf("unexpected errors percentage, got %v, want 12.5", got)
+	}
+	if got := dst.RequestsHour(); got != 3600 {
+		t.Errorf("unexpected requests hour, got %v, want 3600", got)
+	}
+	if got := dst.SleepDuration(); got != time.Second {
+		t.Errorf("unexpected sleep duration, got %v, want %v", got, time.Second)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	var cfg Config
+
+	var notifications int
+
+	cfg.Subscribe(func() {
+		notifications++
+	})
+
+	cfg.SetDurationInterval(1, 2)
+	cfg.SetErrorsPercentage(10)
+	cfg.SetRequestsHour(10)
+	cfg.CopyFrom(&Config{})
+
+	if notifications != 4 {
+		t.Errorf("unexpected number of notifications, got %d, want 4", notifications)
+	}
+
+	cfg.SetErrorsPercentage(1000)
+
+	if notifications != 4 {
+		t.Errorf("notified about an invalid change")
+	}
+}
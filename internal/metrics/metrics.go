@@ -3,9 +3,16 @@ package metrics
 import (
 	"context"
 	"math/rand"
+	"sort"
 	"time"
 
+	"github.com/francescomari/metrics-generator/internal/anomaly"
+	"github.com/francescomari/metrics-generator/internal/events"
+	"github.com/francescomari/metrics-generator/internal/exemplar"
 	"github.com/francescomari/metrics-generator/internal/limits"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type Histogram interface {
@@ -16,22 +23,112 @@ type Counter interface {
 	Inc()
 }
 
+// ExemplarHistogram is implemented by histograms that accept exemplars.
+type ExemplarHistogram interface {
+	ObserveWithExemplar(value float64, exemplar prometheus.Labels)
+}
+
+// ExemplarCounter is implemented by counters that accept exemplars.
+type ExemplarCounter interface {
+	AddWithExemplar(value float64, exemplar prometheus.Labels)
+}
+
+type AccessLog interface {
+	Log(now time.Time, duration float64, failed bool, traceID string)
+}
+
+type Exemplars interface {
+	Exemplar() map[string]string
+}
+
+type Tracer interface {
+	Trace(now time.Time, duration float64, failed bool) (ids map[string]string, sampled bool)
+}
+
+type Publisher interface {
+	Publish(events.Event)
+}
+
+type Recorder interface {
+	Record(t time.Time, duration float64, failed bool)
+}
+
+type Anomalies interface {
+	Effects(now time.Time) anomaly.Effects
+}
+
+type Pause interface {
+	Paused(now time.Time) bool
+}
+
+type Heartbeat interface {
+	Beat(now time.Time, sleep time.Duration)
+}
+
 type Generator struct {
 	Config   *limits.Config
 	Duration Histogram
 	Errors   Counter
+
+	// ErrorsTotal, if set, counts the same errors as Errors, and carries
+	// their exemplars instead of Errors.
+	ErrorsTotal Counter
+
+	// Summary, if set, observes the same durations as Duration.
+	Summary Histogram
+
+	// Exemplars, if set, returns the exemplar attached to the observations
+	// of a simulated request, if Duration and ErrorsTotal accept exemplars.
+	// It is ignored if Tracer is set.
+	Exemplars Exemplars
+
+	// Tracer, if set, traces every simulated request. The IDs of the trace
+	// are the exemplar of the request when the tracer samples it.
+	Tracer Tracer
+
+	// AccessLog, if set, receives every simulated request with the ID of its
+	// trace or of its exemplar, if any.
+	AccessLog AccessLog
+
+	// Events, if set, receives an event for every simulated request.
+	Events Publisher
+
+	// Stats, if set, records every simulated request.
+	Stats Recorder
+
+	// Anomalies, if set, alter the simulated requests on top of the
+	// configuration.
+	Anomalies Anomalies
+
+	// Pause, if set, stops the observation of simulated requests while it
+	// is paused.
+	Pause Pause
+
+	// Heartbeat, if set, is notified at every iteration with the time the
+	// generator is going to sleep for.
+	Heartbeat Heartbeat
+
+	// Logger, if set, receives a debug line for every simulated request when
+	// LogRequests is true.
+	Logger      log.Logger
+	LogRequests bool
 }
 
+// maxSleep is the longest the generator sleeps before evaluating the
+// anomalies again, so that an anomaly changing the rate of the requests takes
+// effect, or stops, without waiting for a long sleep to end.
+const maxSleep = time.Second
+
 func (g *Generator) Run(ctx context.Context) error {
+	var last time.Time
+
 	for {
-		g.Duration.Observe(g.randomDuration())
+		var sleep time.Duration
 
-		if g.shouldFailRequest() {
-			g.Errors.Inc()
-		}
+		last, sleep = g.step(time.Now(), last)
 
 		select {
-		case <-time.After(g.Config.SleepDuration()):
+		case <-time.After(sleep):
 			continue
 		case <-ctx.Done():
 			return ctx.Err()
@@ -39,9 +136,164 @@ func (g *Generator) Run(ctx context.Context) error {
 	}
 }
 
-func (g *Generator) shouldFailRequest() bool {
+// step simulates a request at time now if the interval between requests has
+// elapsed since the last request. It returns the time of the last request and
+// how long to sleep before the next step, at most maxSleep.
+func (g *Generator) step(now, last time.Time) (time.Time, time.Duration) {
+	effects := g.effects(now)
+
+	interval := time.Duration(float64(g.Config.SleepDuration()) / effects.Traffic)
+
+	if now.Sub(last) >= interval {
+		g.simulateRequest(now, effects)
+		last = now
+	}
+
+	sleep := last.Add(interval).Sub(now)
+
+	if sleep > maxSleep {
+		sleep = maxSleep
+	}
+
+	if g.Heartbeat != nil {
+		g.Heartbeat.Beat(now, sleep)
+	}
+
+	return last, sleep
+}
+
+// noEffects are the effects when no anomaly is active.
+var noEffects = anomaly.Effects{Latency: 1, Traffic: 1, ErrorsPercentage: -1}
+
+func (g *Generator) effects(now time.Time) anomaly.Effects {
+	if g.Anomalies == nil {
+		return noEffects
+	}
+
+	return g.Anomalies.Effects(now)
+}
+
+func (g *Generator) paused(now time.Time) bool {
+	return g.Pause != nil && g.Pause.Paused(now)
+}
+
+func (g *Generator) simulateRequest(now time.Time, effects anomaly.Effects) {
+	if effects.Drop || g.paused(now) {
+		return
+	}
+
+	errorsPercentage := g.Config.ErrorsPercentage()
+
+	if effects.ErrorsPercentage >= 0 {
+		errorsPercentage = effects.ErrorsPercentage
+	}
+
+	duration := g.randomDuration() * effects.Latency
+	failed := shouldFailRequest(errorsPercentage)
+
+	labels, traceID := g.trace(now, duration, failed)
+
+	observe(g.Duration, duration, labels)
+
+	if g.Summary != nil {
+		g.Summary.Observe(duration)
+	}
+
+	if failed {
+		g.Errors.Inc()
+	}
+
+	if failed && g.ErrorsTotal != nil {
+		inc(g.ErrorsTotal, labels)
+	}
+
+	if g.Stats != nil {
+		g.Stats.Record(now, duration, failed)
+	}
+
+	if g.AccessLog != nil {
+		g.AccessLog.Log(now, duration, failed, traceID)
+	}
+
+	if g.LogRequests && g.Logger != nil {
+		keyvals := []interface{}{"msg", "Simulated request", "duration", duration, "error", failed}
+
+		names := make([]string, 0, len(labels))
+
+		for name := range labels {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			keyvals = append(keyvals, name, labels[name])
+		}
+
+		if labels == nil && traceID != "" {
+			keyvals = append(keyvals, exemplar.TraceIDLabel, traceID)
+		}
+
+		level.Debug(g.Logger).Log(keyvals...)
+	}
+
+	if g.Events != nil {
+		g.Events.Publish(events.Event{
+			Type: events.TypeRequest,
+			Time: now,
+			Request: &events.Request{
+				Duration: duration,
+				Error:    failed,
+				TraceID:  traceID,
+				Labels:   labels,
+			},
+		})
+	}
+}
+
+// trace returns the labels of the exemplar of a simulated request, if any,
+// and the ID of its trace. Every request is traced if Tracer is set, while
+// only the sampled ones have an exemplar.
+func (g *Generator) trace(now time.Time, duration float64, failed bool) (map[string]string, string) {
+	if g.Tracer != nil {
+		ids, sampled := g.Tracer.Trace(now, duration, failed)
+
+		if !sampled {
+			return nil, ids[exemplar.TraceIDLabel]
+		}
+
+		return ids, ids[exemplar.TraceIDLabel]
+	}
+
+	if g.Exemplars != nil {
+		labels := g.Exemplars.Exemplar()
+		return labels, labels[exemplar.TraceIDLabel]
+	}
+
+	return nil, ""
+}
+
+func observe(h Histogram, value float64, exemplar map[string]string) {
+	if e, ok := h.(ExemplarHistogram); ok && exemplar != nil {
+		e.ObserveWithExemplar(value, exemplar)
+		return
+	}
+
+	h.Observe(value)
+}
+
+func inc(c Counter, exemplar map[string]string) {
+	if e, ok := c.(ExemplarCounter); ok && exemplar != nil {
+		e.AddWithExemplar(1, exemplar)
+		return
+	}
+
+	c.Inc()
+}
+
+func shouldFailRequest(errorsPercentage float64) bool {
 	f := float64(rand.Intn(100000)) / 1000
-	return f < g.Config.ErrorsPercentage()
+	return f < errorsPercentage
 }
 
 func (g *Generator) randomDuration() float64 {
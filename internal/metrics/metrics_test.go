@@ -3,6 +3,13 @@ package metrics
 import (
 	"math/rand"
 	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/anomaly"
+	"github.com/francescomari/metrics-generator/internal/events"
+	"github.com/francescomari/metrics-generator/internal/limits"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // this test does not call the actual implementation because to instantiate we would need
@@ -58,3 +65,575 @@ func TestShouldFailRequest(t *testing.T) {
 		})
 	}
 }
+
+type fakeHistogram struct {
+	values    []float64
+	exemplars []map[string]string
+}
+
+func (h *fakeHistogram) Observe(value float64) {
+	h.values = append(h.values, value)
+	h.exemplars = append(h.exemplars, nil)
+}
+
+func (h *fakeHistogram) ObserveWithExemplar(value float64, exemplar prometheus.Labels) {
+	h.values = append(h.values, value)
+	h.exemplars = append(h.exemplars, exemplar)
+}
+
+type fakeCounter struct {
+	value     float64
+	exemplars []map[string]string
+}
+
+func (c *fakeCounter) Inc() {
+	c.value++
+}
+
+func (c *fakeCounter) AddWithExemplar(value float64, exemplar prometheus.Labels) {
+	c.value += value
+	c.exemplars = append(c.exemplars, exemplar)
+}
+
+type fakeExemplars struct {
+	labels map[string]string
+}
+
+func (e fakeExemplars) Exemplar() map[string]string {
+	return e.labels
+}
+
+type traceCall struct {
+	now      time.Time
+	duration float64
+	failed   bool
+}
+
+type fakeTracer struct {
+	ids     map[string]string
+	sampled bool
+	calls   []traceCall
+}
+
+func (t *fakeTracer) Trace(now time.Time, duration float64, failed bool) (map[string]string, bool) {
+	t.calls = append(t.calls, traceCall{now: now, duration: duration, failed: failed})
+	return t.ids, t.sampled
+}
+
+type accessLogEntry struct {
+	duration float64
+	failed   bool
+	traceID  string
+}
+
+type fakeAccessLog struct {
+	entries []accessLogEntry
+}
+
+func (l *fakeAccessLog) Log(now time.Time, duration float64, failed bool, traceID string) {
+	l.entries = append(l.entries, accessLogEntry{duration: duration, failed: failed, traceID: traceID})
+}
+
+type fakeAnomalies struct {
+	effects anomaly.Effects
+}
+
+func (a fakeAnomalies) Effects(now time.Time) anomaly.Effects {
+	return a.effects
+}
+
+type fakePause struct {
+	paused bool
+}
+
+func (p fakePause) Paused(now time.Time) bool {
+	return p.paused
+}
+
+type fakeHeartbeat struct {
+	sleeps []time.Duration
+}
+
+func (h *fakeHeartbeat) Beat(now time.Time, sleep time.Duration) {
+	h.sleeps = append(h.sleeps, sleep)
+}
+
+type fakePublisher struct {
+	events []events.Event
+}
+
+func (p *fakePublisher) Publish(e events.Event) {
+	p.events = append(p.events, e)
+}
+
+func newConfig(t *testing.T, duration int, errorsPercentage float64) *limits.Config {
+	t.Helper()
+
+	var config limits.Config
+
+	if err := config.SetDurationInterval(duration, duration); err != nil {
+		t.Fatalf("set duration interval: %v", err)
+	}
+
+	if err := config.SetErrorsPercentage(errorsPercentage); err != nil {
+		t.Fatalf("set errors percentage: %v", err)
+	}
+
+	if err := config.SetRequestsHour(3600); err != nil {
+		t.Fatalf("set requests per hour: %v", err)
+	}
+
+	return &config
+}
+
+func TestSimulateRequestEvent(t *testing.T) {
+	var publisher fakePublisher
+
+	labels := map[string]string{"trace_id": "abc"}
+
+	g := Generator{
+		Config:    newConfig(t, 3, 100),
+		Duration:  &fakeHistogram{},
+		Errors:    &fakeCounter{},
+		Exemplars: fakeExemplars{labels: labels},
+		Events:    &publisher,
+	}
+
+	now := time.Unix(1000, 0)
+
+	g.simulateRequest(now, noEffects)
+
+	if len(publisher.events) != 1 {
+		t.Fatalf("invalid number of events: %d", len(publisher.events))
+	}
+
+	want := events.Event{
+		Type: events.TypeRequest,
+		Time: now,
+		Request: &events.Request{
+			Duration: 3,
+			Error:    true,
+			TraceID:  "abc",
+			Labels:   labels,
+		},
+	}
+
+	if diff := cmp.Diff(want, publisher.events[0]); diff != "" {
+		t.Fatalf("invalid event:\n%s", diff)
+	}
+}
+
+func TestSimulateRequest(t *testing.T) {
+	tests := []struct {
+		name             string
+		errorsPercentage float64
+		effects          anomaly.Effects
+		paused           bool
+		wantDurations    []float64
+		wantErrors       float64
+	}{
+		{
+			name:          "baseline",
+			effects:       noEffects,
+			wantDurations: []float64{3},
+		},
+		{
+			name:             "baseline errors",
+			errorsPercentage: 100,
+			effects:          noEffects,
+			wantDurations:    []float64{3},
+			wantErrors:       1,
+		},
+		{
+			name:             "dropped",
+			errorsPercentage: 100,
+			effects:          anomaly.Effects{Latency: 1, Traffic: 1, ErrorsPercentage: -1, Drop: true},
+		},
+		{
+			name:             "paused",
+			errorsPercentage: 100,
+			effects:          noEffects,
+			paused:           true,
+		},
+		{
+			name:          "latency multiplier",
+			effects:       anomaly.Effects{Latency: 2.5, Traffic: 1, ErrorsPercentage: -1},
+			wantDurations: []float64{7.5},
+		},
+		{
+			name:          "flapping override",
+			effects:       anomaly.Effects{Latency: 1, Traffic: 1, ErrorsPercentage: 100},
+			wantDurations: []float64{3},
+			wantErrors:    1,
+		},
+		{
+			name:             "flapping override of the baseline errors",
+			errorsPercentage: 100,
+			effects:          anomaly.Effects{Latency: 1, Traffic: 1, ErrorsPercentage: 0},
+			wantDurations:    []float64{3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				histogram fakeHistogram
+				summary   fakeHistogram
+				errors    fakeCounter
+			)
+
+			g := Generator{
+				Config:   newConfig(t, 3, test.errorsPercentage),
+				Duration: &histogram,
+				Summary:  &summary,
+				Errors:   &errors,
+				Pause:    fakePause{paused: test.paused},
+			}
+
+			g.simulateRequest(time.Unix(1000, 0), test.effects)
+
+			if diff := cmp.Diff(test.wantDurations, histogram.values); diff != "" {
+				t.Errorf("invalid durations:\n%s", diff)
+			}
+
+			if diff := cmp.Diff(test.wantDurations, summary.values); diff != "" {
+				t.Errorf("invalid summary durations:\n%s", diff)
+			}
+
+			if errors.value != test.wantErrors {
+				t.Errorf("invalid errors: got %v, want %v", errors.value, test.wantErrors)
+			}
+		})
+	}
+}
+
+func TestStep(t *testing.T) {
+	tests := []struct {
+		name         string
+		traffic      float64
+		sinceLast    time.Duration
+		wantRequests int
+		wantSleep    time.Duration
+	}{
+		{
+			name:         "baseline",
+			traffic:      1,
+			sinceLast:    time.Hour,
+			wantRequests: 1,
+			wantSleep:    time.Second,
+		},
+		{
+			name:         "traffic multiplier",
+			traffic:      4,
+			sinceLast:    time.Hour,
+			wantRequests: 1,
+			wantSleep:    250 * time.Millisecond,
+		},
+		{
+			name:      "interval not elapsed",
+			traffic:   1,
+			sinceLast: 200 * time.Millisecond,
+			wantSleep: 800 * time.Millisecond,
+		},
+		{
+			name:         "sleep capped",
+			traffic:      0.01,
+			sinceLast:    time.Hour,
+			wantRequests: 1,
+			wantSleep:    maxSleep,
+		},
+		{
+			name:      "sleep capped with interval not elapsed",
+			traffic:   0.01,
+			sinceLast: 2 * time.Second,
+			wantSleep: maxSleep,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				histogram fakeHistogram
+				heartbeat fakeHeartbeat
+			)
+
+			g := Generator{
+				Config:    newConfig(t, 3, 0),
+				Duration:  &histogram,
+				Errors:    &fakeCounter{},
+				Anomalies: fakeAnomalies{effects: anomaly.Effects{Latency: 1, Traffic: test.traffic, ErrorsPercentage: -1}},
+				Heartbeat: &heartbeat,
+			}
+
+			now := time.Unix(1000, 0)
+			last := now.Add(-test.sinceLast)
+
+			gotLast, sleep := g.step(now, last)
+
+			if len(histogram.values) != test.wantRequests {
+				t.Errorf("invalid number of requests: got %d, want %d", len(histogram.values), test.wantRequests)
+			}
+
+			if test.wantRequests > 0 && !gotLast.Equal(now) {
+				t.Errorf("invalid time of the last request: got %v, want %v", gotLast, now)
+			}
+
+			if test.wantRequests == 0 && !gotLast.Equal(last) {
+				t.Errorf("invalid time of the last request: got %v, want %v", gotLast, last)
+			}
+
+			if sleep != test.wantSleep {
+				t.Errorf("invalid sleep: got %v, want %v", sleep, test.wantSleep)
+			}
+
+			if diff := cmp.Diff([]time.Duration{test.wantSleep}, heartbeat.sleeps); diff != "" {
+				t.Errorf("invalid heartbeats:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStepAfterAnomalyExpires(t *testing.T) {
+	anomalies := fakeAnomalies{effects: anomaly.Effects{Latency: 1, Traffic: 0.01, ErrorsPercentage: -1}}
+
+	var histogram fakeHistogram
+
+	g := Generator{
+		Config:    newConfig(t, 3, 0),
+		Duration:  &histogram,
+		Errors:    &fakeCounter{},
+		Anomalies: &anomalies,
+	}
+
+	now := time.Unix(1000, 0)
+
+	last, sleep := g.step(now, time.Time{})
+
+	if len(histogram.values) != 1 {
+		t.Fatalf("invalid number of requests: %d", len(histogram.values))
+	}
+
+	// The anomaly expires while the generator sleeps, and the next request
+	// is simulated as soon as the configured interval has elapsed.
+	anomalies.effects = noEffects
+
+	now = now.Add(sleep)
+
+	g.step(now, last)
+
+	if len(histogram.values) != 2 {
+		t.Fatalf("invalid number of requests: %d", len(histogram.values))
+	}
+}
+
+func TestSimulateRequestExemplars(t *testing.T) {
+	labels := map[string]string{"trace_id": "abc", "span_id": "def"}
+
+	tests := []struct {
+		name                  string
+		errorsPercentage      float64
+		exemplar              map[string]string
+		tracer                *fakeTracer
+		wantDurationExemplars []map[string]string
+		wantErrorsExemplars   []map[string]string
+	}{
+		{
+			name:                  "failed request",
+			errorsPercentage:      100,
+			exemplar:              labels,
+			wantDurationExemplars: []map[string]string{labels},
+			wantErrorsExemplars:   []map[string]string{labels},
+		},
+		{
+			name:                  "successful request",
+			exemplar:              labels,
+			wantDurationExemplars: []map[string]string{labels},
+		},
+		{
+			name:                  "not sampled",
+			errorsPercentage:      100,
+			wantDurationExemplars: []map[string]string{nil},
+		},
+		{
+			name:                  "traced",
+			errorsPercentage:      100,
+			tracer:                &fakeTracer{ids: labels, sampled: true},
+			wantDurationExemplars: []map[string]string{labels},
+			wantErrorsExemplars:   []map[string]string{labels},
+		},
+		{
+			name:                  "traced but not sampled",
+			errorsPercentage:      100,
+			exemplar:              map[string]string{"trace_id": "ignored"},
+			tracer:                &fakeTracer{ids: labels},
+			wantDurationExemplars: []map[string]string{nil},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				histogram   fakeHistogram
+				errors      fakeCounter
+				errorsTotal fakeCounter
+			)
+
+			g := Generator{
+				Config:      newConfig(t, 3, test.errorsPercentage),
+				Duration:    &histogram,
+				Errors:      &errors,
+				ErrorsTotal: &errorsTotal,
+				Exemplars:   fakeExemplars{labels: test.exemplar},
+			}
+
+			// Avoid a typed nil in the interface.
+			if test.tracer != nil {
+				g.Tracer = test.tracer
+			}
+
+			g.simulateRequest(time.Unix(1000, 0), noEffects)
+
+			if diff := cmp.Diff(test.wantDurationExemplars, histogram.exemplars); diff != "" {
+				t.Errorf("invalid duration exemplars:\n%s", diff)
+			}
+
+			if diff := cmp.Diff(test.wantErrorsExemplars, errorsTotal.exemplars); diff != "" {
+				t.Errorf("invalid errors exemplars:\n%s", diff)
+			}
+
+			if errors.value != errorsTotal.value {
+				t.Errorf("invalid errors: got %v and %v", errors.value, errorsTotal.value)
+			}
+
+			if len(errors.exemplars) > 0 {
+				t.Errorf("exemplars attached to the legacy errors counter: %v", errors.exemplars)
+			}
+		})
+	}
+}
+
+func TestSimulateRequestTrace(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	tests := []struct {
+		name             string
+		errorsPercentage float64
+		effects          anomaly.Effects
+		want             []traceCall
+	}{
+		{
+			name:    "successful request",
+			effects: noEffects,
+			want:    []traceCall{{now: now, duration: 3}},
+		},
+		{
+			name:             "failed request",
+			errorsPercentage: 100,
+			effects:          noEffects,
+			want:             []traceCall{{now: now, duration: 3, failed: true}},
+		},
+		{
+			name:    "latency multiplier",
+			effects: anomaly.Effects{Latency: 2, Traffic: 1, ErrorsPercentage: -1},
+			want:    []traceCall{{now: now, duration: 6}},
+		},
+		{
+			name:    "dropped",
+			effects: anomaly.Effects{Latency: 1, Traffic: 1, ErrorsPercentage: -1, Drop: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tracer fakeTracer
+
+			g := Generator{
+				Config:   newConfig(t, 3, test.errorsPercentage),
+				Duration: &fakeHistogram{},
+				Errors:   &fakeCounter{},
+				Tracer:   &tracer,
+			}
+
+			g.simulateRequest(now, test.effects)
+
+			if diff := cmp.Diff(test.want, tracer.calls, cmp.AllowUnexported(traceCall{})); diff != "" {
+				t.Errorf("invalid traced requests:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSimulateRequestAccessLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		exemplar map[string]string
+		tracer   *fakeTracer
+		effects  anomaly.Effects
+		want     []accessLogEntry
+	}{
+		{
+			name:     "sampled",
+			exemplar: map[string]string{"trace_id": "abc", "span_id": "def"},
+			effects:  noEffects,
+			want:     []accessLogEntry{{duration: 3, failed: true, traceID: "abc"}},
+		},
+		{
+			name:    "not sampled",
+			effects: noEffects,
+			want:    []accessLogEntry{{duration: 3, failed: true}},
+		},
+		{
+			name:    "traced",
+			tracer:  &fakeTracer{ids: map[string]string{"trace_id": "abc", "span_id": "def"}, sampled: true},
+			effects: noEffects,
+			want:    []accessLogEntry{{duration: 3, failed: true, traceID: "abc"}},
+		},
+		{
+			name:    "traced but not sampled",
+			tracer:  &fakeTracer{ids: map[string]string{"trace_id": "abc", "span_id": "def"}},
+			effects: noEffects,
+			want:    []accessLogEntry{{duration: 3, failed: true, traceID: "abc"}},
+		},
+		{
+			name:     "dropped",
+			exemplar: map[string]string{"trace_id": "abc", "span_id": "def"},
+			effects:  anomaly.Effects{Latency: 1, Traffic: 1, ErrorsPercentage: -1, Drop: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				accessLog fakeAccessLog
+				publisher fakePublisher
+			)
+
+			g := Generator{
+				Config:    newConfig(t, 3, 100),
+				Duration:  &fakeHistogram{},
+				Errors:    &fakeCounter{},
+				Exemplars: fakeExemplars{labels: test.exemplar},
+				AccessLog: &accessLog,
+				Events:    &publisher,
+			}
+
+			// Avoid a typed nil in the interface.
+			if test.tracer != nil {
+				g.Tracer = test.tracer
+			}
+
+			g.simulateRequest(time.Unix(1000, 0), test.effects)
+
+			if diff := cmp.Diff(test.want, accessLog.entries, cmp.AllowUnexported(accessLogEntry{})); diff != "" {
+				t.Fatalf("invalid access log:\n%s", diff)
+			}
+
+			// Events carry the same trace ID as the access log.
+			for i, entry := range accessLog.entries {
+				if got := publisher.events[i].Request.TraceID; got != entry.traceID {
+					t.Errorf("invalid trace ID in event: got %q, want %q", got, entry.traceID)
+				}
+			}
+		})
+	}
+}
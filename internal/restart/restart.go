@@ -0,0 +1,306 @@
+// Package restart simulates restarts of the process exposing the synthetic
+// metrics. A restart resets the synthetic counters, histograms and summaries to zero and
+// moves the start time of the process forward, like a real restart would.
+package restart
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// processStartTimeDesc is the descriptor used by the process collector of
+// the Prometheus client for process_start_time_seconds.
+var processStartTimeDesc = prometheus.NewDesc(
+	"process_start_time_seconds",
+	"Start time of the process since unix epoch in seconds.",
+	nil, nil,
+)
+
+// Process owns the synthetic metrics that are reset when the process is
+// restarted. Metrics must be created with NewHistogram, NewSummary and
+// NewCounter before they are registered.
+type Process struct {
+	// Label, if set, is added to every metric of the process. Its value is
+	// the start time of the process as a Unix timestamp, so that every
+	// restart produces new series.
+	Label string
+
+	// Start is the time the process started, used as the value of Label
+	// until the first restart.
+	Start time.Time
+
+	mu        sync.RWMutex
+	restarts  int
+	start     time.Time
+	observers []*observer
+	counters  []*Counter
+}
+
+// NewHistogram returns a histogram that is reset at every restart.
+func (p *Process) NewHistogram(opts prometheus.HistogramOpts) *Histogram {
+	return &Histogram{p.newObserver(func(labels prometheus.Labels) observerCollector {
+		opts := opts
+		opts.ConstLabels = mergeLabels(opts.ConstLabels, labels)
+		return prometheus.NewHistogram(opts)
+	})}
+}
+
+// NewSummary returns a summary that is reset at every restart.
+func (p *Process) NewSummary(opts prometheus.SummaryOpts) *Summary {
+	return &Summary{p.newObserver(func(labels prometheus.Labels) observerCollector {
+		opts := opts
+		opts.ConstLabels = mergeLabels(opts.ConstLabels, labels)
+		return prometheus.NewSummary(opts)
+	})}
+}
+
+func (p *Process) newObserver(create func(labels prometheus.Labels) observerCollector) *observer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	o := &observer{process: p, create: create}
+	o.reset(p.labels())
+	p.observers = append(p.observers, o)
+
+	return o
+}
+
+// NewCounter returns a counter that is reset at every restart.
+func (p *Process) NewCounter(opts prometheus.CounterOpts) *Counter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c := &Counter{process: p, opts: opts}
+	c.reset(p.labels())
+	p.counters = append(p.counters, c)
+
+	return c
+}
+
+// Restart simulates a restart of the process at time now.
+func (p *Process) Restart(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.restarts++
+	p.start = now
+
+	labels := p.labels()
+
+	for _, o := range p.observers {
+		o.reset(labels)
+	}
+
+	for _, c := range p.counters {
+		c.reset(labels)
+	}
+}
+
+// Restarts returns the number of simulated restarts.
+func (p *Process) Restarts() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.restarts
+}
+
+// StartTime returns the start time of the process, which is the time of the
+// last restart or Start if the process was never restarted.
+func (p *Process) StartTime() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.startTime()
+}
+
+func (p *Process) startTime() time.Time {
+	if p.restarts == 0 {
+		return p.Start
+	}
+
+	return p.start
+}
+
+func (p *Process) labels() prometheus.Labels {
+	if p.Label == "" {
+		return nil
+	}
+
+	return prometheus.Labels{
+		p.Label: strconv.FormatInt(p.startTime().Unix(), 10),
+	}
+}
+
+// describe sends the descriptor of a metric of the process, unless the
+// value of Label changes at every restart. In that case the metric is an
+// unchecked collector, because its descriptor is not constant.
+func (p *Process) describe(ch chan<- *prometheus.Desc, c prometheus.Collector) {
+	if p.Label != "" {
+		return
+	}
+
+	c.Describe(ch)
+}
+
+// observerCollector is implemented by histograms and summaries.
+type observerCollector interface {
+	prometheus.Observer
+	prometheus.Collector
+}
+
+// observer is an observer that is recreated when its process restarts.
+type observer struct {
+	process *Process
+	create  func(labels prometheus.Labels) observerCollector
+	current observerCollector
+}
+
+func (o *observer) reset(labels prometheus.Labels) {
+	o.current = o.create(labels)
+}
+
+func (o *observer) Observe(value float64) {
+	o.process.mu.RLock()
+	defer o.process.mu.RUnlock()
+
+	o.current.Observe(value)
+}
+
+func (o *observer) Describe(ch chan<- *prometheus.Desc) {
+	o.process.mu.RLock()
+	defer o.process.mu.RUnlock()
+
+	o.process.describe(ch, o.current)
+}
+
+func (o *observer) Collect(ch chan<- prometheus.Metric) {
+	o.process.mu.RLock()
+	defer o.process.mu.RUnlock()
+
+	o.current.Collect(ch)
+}
+
+// Histogram is a histogram that is reset when its process restarts.
+type Histogram struct {
+	*observer
+}
+
+// ObserveWithExemplar observes a value with an exemplar, as described by
+// prometheus.ExemplarObserver.
+func (h *Histogram) ObserveWithExemplar(value float64, exemplar prometheus.Labels) {
+	h.process.mu.RLock()
+	defer h.process.mu.RUnlock()
+
+	h.current.(prometheus.ExemplarObserver).ObserveWithExemplar(value, exemplar)
+}
+
+// Summary is a summary that is reset when its process restarts.
+type Summary struct {
+	*observer
+}
+
+// Counter is a counter that is reset when its process restarts.
+type Counter struct {
+	process *Process
+	opts    prometheus.CounterOpts
+	current prometheus.Counter
+}
+
+func (c *Counter) reset(labels prometheus.Labels) {
+	opts := c.opts
+	opts.ConstLabels = mergeLabels(opts.ConstLabels, labels)
+	c.current = prometheus.NewCounter(opts)
+}
+
+func (c *Counter) Inc() {
+	c.process.mu.RLock()
+	defer c.process.mu.RUnlock()
+
+	c.current.Inc()
+}
+
+// AddWithExemplar adds a value with an exemplar, as described by
+// prometheus.ExemplarAdder.
+func (c *Counter) AddWithExemplar(value float64, exemplar prometheus.Labels) {
+	c.process.mu.RLock()
+	defer c.process.mu.RUnlock()
+
+	c.current.(prometheus.ExemplarAdder).AddWithExemplar(value, exemplar)
+}
+
+func (c *Counter) Describe(ch chan<- *prometheus.Desc) {
+	c.process.mu.RLock()
+	defer c.process.mu.RUnlock()
+
+	c.process.describe(ch, c.current)
+}
+
+func (c *Counter) Collect(ch chan<- prometheus.Metric) {
+	c.process.mu.RLock()
+	defer c.process.mu.RUnlock()
+
+	c.current.Collect(ch)
+}
+
+// WrapProcessCollector returns a collector that forwards the metrics of the
+// process collector of the Prometheus client, but reports the start time of
+// the simulated process as process_start_time_seconds after a restart.
+func (p *Process) WrapProcessCollector(c prometheus.Collector) prometheus.Collector {
+	return &processCollector{process: p, collector: c}
+}
+
+type processCollector struct {
+	process   *Process
+	collector prometheus.Collector
+}
+
+func (c *processCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.collector.Describe(ch)
+}
+
+func (c *processCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.process.Restarts() == 0 {
+		c.collector.Collect(ch)
+		return
+	}
+
+	metrics := make(chan prometheus.Metric)
+
+	go func() {
+		c.collector.Collect(metrics)
+		close(metrics)
+	}()
+
+	for m := range metrics {
+		if m.Desc().String() == processStartTimeDesc.String() {
+			continue
+		}
+
+		ch <- m
+	}
+
+	start := c.process.StartTime()
+
+	ch <- prometheus.MustNewConstMetric(processStartTimeDesc, prometheus.GaugeValue, float64(start.UnixNano())/1e9)
+}
+
+func mergeLabels(a, b prometheus.Labels) prometheus.Labels {
+	if len(b) == 0 {
+		return a
+	}
+
+	merged := make(prometheus.Labels, len(a)+len(b))
+
+	for k, v := range a {
+		merged[k] = v
+	}
+
+	for k, v := range b {
+		merged[k] = v
+	}
+
+	return merged
+}
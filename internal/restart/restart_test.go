@@ -0,0 +1,196 @@
+package restart_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/restart"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRestart(t *testing.T) {
+	var process restart.Process
+
+	histogram := process.NewHistogram(prometheus.HistogramOpts{Name: "test_seconds", Help: "Test", Buckets: []float64{1}})
+	counter := process.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test"})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(histogram, counter)
+
+	histogram.Observe(1)
+	counter.Inc()
+
+	if value := testutil.ToFloat64(counter); value != 1 {
+		t.Fatalf("invalid counter value: %v", value)
+	}
+
+	now := time.Unix(1000, 0)
+
+	process.Restart(now)
+
+	if value := testutil.ToFloat64(counter); value != 0 {
+		t.Fatalf("counter not reset: %v", value)
+	}
+
+	expected := `
+		# HELP test_seconds Test
+		# TYPE test_seconds histogram
+		test_seconds_bucket{le="1"} 0
+		test_seconds_bucket{le="+Inf"} 0
+		test_seconds_sum 0
+		test_seconds_count 0
+	`
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "test_seconds"); err != nil {
+		t.Fatalf("histogram not reset: %v", err)
+	}
+
+	if restarts := process.Restarts(); restarts != 1 {
+		t.Fatalf("invalid number of restarts: %d", restarts)
+	}
+
+	if start := process.StartTime(); !start.Equal(now) {
+		t.Fatalf("invalid start time: %v", start)
+	}
+}
+
+func TestRestartLabel(t *testing.T) {
+	process := restart.Process{
+		Label: "start_time",
+		Start: time.Unix(1000, 0),
+	}
+
+	counter := process.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test"})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(counter)
+
+	counter.Inc()
+
+	expected := `
+		# HELP test_total Test
+		# TYPE test_total counter
+		test_total{start_time="1000"} 1
+	`
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Fatalf("invalid metrics before restart: %v", err)
+	}
+
+	process.Restart(time.Unix(2000, 0))
+
+	expected = `
+		# HELP test_total Test
+		# TYPE test_total counter
+		test_total{start_time="2000"} 0
+	`
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Fatalf("invalid metrics after restart: %v", err)
+	}
+}
+
+func TestWrapProcessCollector(t *testing.T) {
+	var process restart.Process
+
+	desc := prometheus.NewDesc("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", nil, nil)
+
+	collector := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "process_start_time_seconds",
+		Help: "Start time of the process since unix epoch in seconds.",
+	}, func() float64 {
+		return 1000
+	})
+
+	if collector.Desc().String() != desc.String() {
+		t.Fatalf("unexpected descriptor: %v", collector.Desc())
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(process.WrapProcessCollector(collector))
+
+	if value := testutil.ToFloat64(collector); value != 1000 {
+		t.Fatalf("invalid start time before restart: %v", value)
+	}
+
+	process.Restart(time.Unix(2000, 0))
+
+	expected := `
+		# HELP process_start_time_seconds Start time of the process since unix epoch in seconds.
+		# TYPE process_start_time_seconds gauge
+		process_start_time_seconds 2000
+	`
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Fatalf("invalid start time after restart: %v", err)
+	}
+}
+
+func TestRestartSummary(t *testing.T) {
+	var process restart.Process
+
+	summary := process.NewSummary(prometheus.SummaryOpts{Name: "test_seconds", Help: "Test", Objectives: map[float64]float64{0.5: 0.05}})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(summary)
+
+	summary.Observe(1)
+
+	process.Restart(time.Unix(1000, 0))
+
+	expected := `
+		# HELP test_seconds Test
+		# TYPE test_seconds summary
+		test_seconds{quantile="0.5"} NaN
+		test_seconds_sum 0
+		test_seconds_count 0
+	`
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "test_seconds"); err != nil {
+		t.Fatalf("summary not reset: %v", err)
+	}
+}
+
+func TestRestartExemplars(t *testing.T) {
+	var process restart.Process
+
+	histogram := process.NewHistogram(prometheus.HistogramOpts{Name: "test_seconds", Help: "Test", Buckets: []float64{1}})
+	counter := process.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test"})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(histogram, counter)
+
+	histogram.ObserveWithExemplar(1, prometheus.Labels{"trace_id": "a"})
+	counter.AddWithExemplar(1, prometheus.Labels{"trace_id": "b"})
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	exemplars := map[string]string{}
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			if e := metric.GetCounter().GetExemplar(); e != nil {
+				exemplars[family.GetName()] = e.GetLabel()[0].GetValue()
+			}
+
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				if e := bucket.GetExemplar(); e != nil {
+					exemplars[family.GetName()] = e.GetLabel()[0].GetValue()
+				}
+			}
+		}
+	}
+
+	if exemplars["test_seconds"] != "a" {
+		t.Fatalf("invalid histogram exemplar: %q", exemplars["test_seconds"])
+	}
+
+	if exemplars["test_total"] != "b" {
+		t.Fatalf("invalid counter exemplar: %q", exemplars["test_total"])
+	}
+}
@@ -0,0 +1,377 @@
+// Package scenario runs a timeline of changes against a running generator,
+// using the same API actions as the commands controlling the generator.
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/francescomari/metrics-generator/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+// Client is the part of the API of the generator used by the steps of a
+// scenario. It is implemented by the client in pkg/client.
+type Client interface {
+	SetDurationInterval(ctx context.Context, min, max int) error
+	SetErrorsPercentage(ctx context.Context, value float64) error
+	SetRequestsHour(ctx context.Context, value int) error
+	Restart(ctx context.Context) error
+	HideSeries(ctx context.Context, metric string, labels map[string]string, duration time.Duration) (*client.HiddenSeries, error)
+	Pause(ctx context.Context, duration time.Duration) error
+	Resume(ctx context.Context) error
+}
+
+// Scenario is a sequence of steps, in the order they are run.
+type Scenario struct {
+	Steps []Step `yaml:"steps"`
+}
+
+// Step is a set of changes applied to the generator when the time since the
+// start of the scenario reaches At. The settings are changed first, in the
+// order of their names, followed by the other actions in the order they are
+// declared below.
+type Step struct {
+	// At is the offset of the step from the start of the scenario. Steps
+	// must be sorted by offset.
+	At time.Duration `yaml:"at"`
+
+	// Set changes the settings named by the keys. The values have the same
+	// format as the values of the set command, e.g. "15,45" for the duration
+	// interval.
+	Set map[string]string `yaml:"set"`
+
+	// Restart, if true, simulates a restart of the generator.
+	Restart bool `yaml:"restart"`
+
+	// Hide, if set, hides series from the metrics endpoint.
+	Hide *Hide `yaml:"hide"`
+
+	// Pause, if set, pauses the observation of simulated requests.
+	Pause *Pause `yaml:"pause"`
+
+	// Resume, if true, resumes the observation of simulated requests.
+	Resume bool `yaml:"resume"`
+}
+
+// Hide hides the series of a metric matching a set of labels, or every series
+// of the metric if Labels is empty.
+type Hide struct {
+	Metric string            `yaml:"metric"`
+	Labels map[string]string `yaml:"labels"`
+
+	// For is how long the series are hidden. If zero, they are hidden until
+	// they are shown through the API.
+	For time.Duration `yaml:"for"`
+}
+
+// Pause pauses the observation of simulated requests.
+type Pause struct {
+	// For is how long observation is paused. If zero, it is paused until a
+	// later step resumes it.
+	For time.Duration `yaml:"for"`
+}
+
+// Load reads a scenario from the YAML file at path. See Parse for the
+// expected format.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %v", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse parses a scenario from the content of a YAML file, for example:
+//
+//	steps:
+//	  - set:
+//	      errors-percentage: 25
+//	  - at: 5m
+//	    set:
+//	      errors-percentage: 0
+//
+// Every step is validated, so that a scenario doesn't fail half-way because
+// of a mistake in a later step.
+func Parse(data []byte) (*Scenario, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var scenario Scenario
+
+	if err := decoder.Decode(&scenario); err != nil {
+		return nil, fmt.Errorf("parse YAML: %v", err)
+	}
+
+	if len(scenario.Steps) == 0 {
+		return nil, fmt.Errorf("no steps")
+	}
+
+	var last time.Duration
+
+	for i, step := range scenario.Steps {
+		if err := step.validate(last); err != nil {
+			return nil, fmt.Errorf("step %d: %v", i+1, err)
+		}
+
+		last = step.At
+	}
+
+	return &scenario, nil
+}
+
+func (s Step) validate(last time.Duration) error {
+	if s.At < 0 {
+		return fmt.Errorf("negative offset %v", s.At)
+	}
+
+	if s.At < last {
+		return fmt.Errorf("offset %v is before the offset of the previous step", s.At)
+	}
+
+	if len(s.Set) == 0 && !s.Restart && s.Hide == nil && s.Pause == nil && !s.Resume {
+		return fmt.Errorf("no action")
+	}
+
+	if s.Hide != nil && s.Hide.Metric == "" {
+		return fmt.Errorf("hide: metric is required")
+	}
+
+	if s.Hide != nil && s.Hide.For < 0 {
+		return fmt.Errorf("hide: negative duration %v", s.Hide.For)
+	}
+
+	if s.Pause != nil && s.Pause.For < 0 {
+		return fmt.Errorf("pause: negative duration %v", s.Pause.For)
+	}
+
+	for name, value := range s.Set {
+		if _, err := ParseSetting(name, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// actions returns the changes of the step, in the order they are applied.
+func (s Step) actions() []action {
+	var actions []action
+
+	var names []string
+
+	for name := range s.Set {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		// The settings are validated by Parse.
+		apply, _ := ParseSetting(name, s.Set[name])
+
+		actions = append(actions, action{
+			description: fmt.Sprintf("set %s %s", name, s.Set[name]),
+			apply:       apply,
+		})
+	}
+
+	if s.Restart {
+		actions = append(actions, action{
+			description: "restart",
+			apply: func(ctx context.Context, c Client) error {
+				return c.Restart(ctx)
+			},
+		})
+	}
+
+	if h := s.Hide; h != nil {
+		actions = append(actions, action{
+			description: "hide " + h.description(),
+			apply: func(ctx context.Context, c Client) error {
+				_, err := c.HideSeries(ctx, h.Metric, h.Labels, h.For)
+				return err
+			},
+		})
+	}
+
+	if p := s.Pause; p != nil {
+		description := "pause"
+
+		if p.For > 0 {
+			description += " for " + p.For.String()
+		}
+
+		actions = append(actions, action{
+			description: description,
+			apply: func(ctx context.Context, c Client) error {
+				return c.Pause(ctx, p.For)
+			},
+		})
+	}
+
+	if s.Resume {
+		actions = append(actions, action{
+			description: "resume",
+			apply: func(ctx context.Context, c Client) error {
+				return c.Resume(ctx)
+			},
+		})
+	}
+
+	return actions
+}
+
+// description returns the selected series in the format of a PromQL
+// selector, followed by the duration.
+func (h *Hide) description() string {
+	var names []string
+
+	for name := range h.Labels {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var matchers []string
+
+	for _, name := range names {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", name, h.Labels[name]))
+	}
+
+	description := h.Metric
+
+	if len(matchers) > 0 {
+		description += "{" + strings.Join(matchers, ",") + "}"
+	}
+
+	if h.For > 0 {
+		description += " for " + h.For.String()
+	}
+
+	return description
+}
+
+type action struct {
+	description string
+	apply       func(ctx context.Context, c Client) error
+}
+
+// Setting applies the value of a setting to the generator.
+type Setting func(ctx context.Context, c Client) error
+
+// ParseSetting parses the value of the setting with the given name. The
+// duration interval is in the form min,max. The value is checked by the
+// generator when the setting is applied.
+func ParseSetting(name, value string) (Setting, error) {
+	switch name {
+	case "duration-interval":
+		parts := strings.Split(value, ",")
+
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("duration interval must be in the form min,max")
+		}
+
+		min, minErr := strconv.Atoi(strings.TrimSpace(parts[0]))
+		max, maxErr := strconv.Atoi(strings.TrimSpace(parts[1]))
+
+		if minErr != nil || maxErr != nil {
+			return nil, fmt.Errorf("duration interval must be a pair of numbers")
+		}
+
+		return func(ctx context.Context, c Client) error {
+			return c.SetDurationInterval(ctx, min, max)
+		}, nil
+	case "errors-percentage":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("errors percentage must be a number")
+		}
+
+		return func(ctx context.Context, c Client) error {
+			return c.SetErrorsPercentage(ctx, v)
+		}, nil
+	case "requests-hour":
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("requests per hour must be a number")
+		}
+
+		return func(ctx context.Context, c Client) error {
+			return c.SetRequestsHour(ctx, v)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown setting %q", name)
+	}
+}
+
+// Runner runs scenarios against a generator.
+type Runner struct {
+	Client Client
+
+	// Timeout, if positive, limits the duration of every call to the API.
+	Timeout time.Duration
+
+	// OnAction, if not nil, is called after every change applied to the
+	// generator, with the offset of its step and a description of the
+	// change.
+	OnAction func(at time.Duration, description string)
+}
+
+// Run runs the steps of the scenario, waiting before every step until its
+// offset has elapsed since Run was called. Run stops at the first change
+// that fails, or when the context is cancelled.
+func (r *Runner) Run(ctx context.Context, scenario *Scenario) error {
+	start := time.Now()
+
+	for i, step := range scenario.Steps {
+		if err := sleep(ctx, time.Until(start.Add(step.At))); err != nil {
+			return err
+		}
+
+		for _, action := range step.actions() {
+			if err := r.apply(ctx, action); err != nil {
+				return fmt.Errorf("step %d: %s: %v", i+1, action.description, err)
+			}
+
+			if r.OnAction != nil {
+				r.OnAction(step.At, action.description)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) apply(ctx context.Context, action action) error {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	return action.apply(ctx, r.Client)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
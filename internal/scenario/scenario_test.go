@@ -0,0 +1,261 @@
+package scenario_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/scenario"
+	"github.com/francescomari/metrics-generator/pkg/client"
+	"github.com/google/go-cmp/cmp"
+)
+
+type fakeClient struct {
+	calls []string
+	err   error
+}
+
+func (c *fakeClient) record(call string) error {
+	c.calls = append(c.calls, call)
+	return c.err
+}
+
+func (c *fakeClient) SetDurationInterval(ctx context.Context, min, max int) error {
+	return c.record(fmt.Sprintf("duration-interval %d,%d", min, max))
+}
+
+func (c *fakeClient) SetErrorsPercentage(ctx context.Context, value float64) error {
+	return c.record(fmt.Sprintf("errors-percentage %v", value))
+}
+
+func (c *fakeClient) SetRequestsHour(ctx context.Context, value int) error {
+	return c.record(fmt.Sprintf("requests-hour %d", value))
+}
+
+func (c *fakeClient) Restart(ctx context.Context) error {
+	return c.record("restart")
+}
+
+func (c *fakeClient) HideSeries(ctx context.Context, metric string, labels map[string]string, duration time.Duration) (*client.HiddenSeries, error) {
+	return &client.HiddenSeries{ID: "1", Metric: metric, Labels: labels}, c.record(fmt.Sprintf("hide %s %v %v", metric, labels, duration))
+}
+
+func (c *fakeClient) Pause(ctx context.Context, duration time.Duration) error {
+	return c.record(fmt.Sprintf("pause %v", duration))
+}
+
+func (c *fakeClient) Resume(ctx context.Context) error {
+	return c.record("resume")
+}
+
+func TestParse(t *testing.T) {
+	data := `
+steps:
+  - set:
+      errors-percentage: 25
+      duration-interval: 15,45
+  - at: 5m
+    set:
+      requests-hour: 3600
+  - at: 10m
+    restart: true
+  - at: 15m
+    hide:
+      metric: metrics_generator_request_duration_seconds
+      labels:
+        le: "+Inf"
+      for: 1m
+    pause:
+      for: 30s
+  - at: 20m
+    resume: true
+`
+
+	s, err := scenario.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	want := &scenario.Scenario{
+		Steps: []scenario.Step{
+			{Set: map[string]string{"errors-percentage": "25", "duration-interval": "15,45"}},
+			{At: 5 * time.Minute, Set: map[string]string{"requests-hour": "3600"}},
+			{At: 10 * time.Minute, Restart: true},
+			{
+				At: 15 * time.Minute,
+				Hide: &scenario.Hide{
+					Metric: "metrics_generator_request_duration_seconds",
+					Labels: map[string]string{"le": "+Inf"},
+					For:    time.Minute,
+				},
+				Pause: &scenario.Pause{For: 30 * time.Second},
+			},
+			{At: 20 * time.Minute, Resume: true},
+		},
+	}
+
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Fatalf("invalid scenario:\n%s", diff)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ``},
+		{"no steps", `steps: []`},
+		{"unknown field", `steps: [{at: 1s, boom: true}]`},
+		{"invalid offset", `steps: [{at: boom, set: {requests-hour: 1}}]`},
+		{"negative offset", `steps: [{at: -1s, set: {requests-hour: 1}}]`},
+		{"unsorted offsets", `steps: [{at: 2s, set: {requests-hour: 1}}, {at: 1s, set: {requests-hour: 2}}]`},
+		{"no action", `steps: [{at: 1s}]`},
+		{"no restart", `steps: [{restart: false}]`},
+		{"no metric to hide", `steps: [{hide: {for: 1m}}]`},
+		{"negative hide duration", `steps: [{hide: {metric: up, for: -1m}}]`},
+		{"negative pause duration", `steps: [{pause: {for: -1m}}]`},
+		{"unknown setting", `steps: [{set: {boom: 1}}]`},
+		{"invalid duration interval", `steps: [{set: {duration-interval: 15}}]`},
+		{"invalid errors percentage", `steps: [{set: {errors-percentage: boom}}]`},
+		{"invalid requests hour", `steps: [{set: {requests-hour: 1.5}}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := scenario.Parse([]byte(test.data)); err == nil {
+				t.Fatalf("no error returned")
+			}
+		})
+	}
+}
+
+func TestRunnerRun(t *testing.T) {
+	s, err := scenario.Parse([]byte(`
+steps:
+  - set:
+      requests-hour: 3600
+      errors-percentage: 25
+  - at: 50ms
+    restart: true
+    set:
+      duration-interval: 15,45
+  - at: 50ms
+    resume: true
+    pause: {}
+    hide:
+      metric: up
+      labels:
+        job: api
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	var (
+		fake         fakeClient
+		offsets      []time.Duration
+		descriptions []string
+	)
+
+	runner := scenario.Runner{
+		Client: &fake,
+		OnAction: func(at time.Duration, description string) {
+			offsets = append(offsets, at)
+			descriptions = append(descriptions, description)
+		},
+	}
+
+	start := time.Now()
+
+	if err := runner.Run(context.Background(), s); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("scenario completed after %v", elapsed)
+	}
+
+	wantCalls := []string{
+		"errors-percentage 25",
+		"requests-hour 3600",
+		"duration-interval 15,45",
+		"restart",
+		"hide up map[job:api] 0s",
+		"pause 0s",
+		"resume",
+	}
+
+	if diff := cmp.Diff(wantCalls, fake.calls); diff != "" {
+		t.Fatalf("invalid calls:\n%s", diff)
+	}
+
+	wantOffsets := []time.Duration{0, 0, 50 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}
+
+	if diff := cmp.Diff(wantOffsets, offsets); diff != "" {
+		t.Fatalf("invalid offsets:\n%s", diff)
+	}
+
+	wantDescriptions := []string{
+		"set errors-percentage 25",
+		"set requests-hour 3600",
+		"set duration-interval 15,45",
+		"restart",
+		`hide up{job="api"}`,
+		"pause",
+		"resume",
+	}
+
+	if diff := cmp.Diff(wantDescriptions, descriptions); diff != "" {
+		t.Fatalf("invalid descriptions:\n%s", diff)
+	}
+}
+
+func TestRunnerRunError(t *testing.T) {
+	s, err := scenario.Parse([]byte(`
+steps:
+  - set:
+      requests-hour: 3600
+  - set:
+      errors-percentage: 25
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	fake := fakeClient{err: errors.New("boom")}
+
+	runner := scenario.Runner{Client: &fake}
+
+	if err := runner.Run(context.Background(), s); err == nil {
+		t.Fatalf("no error returned")
+	}
+
+	if diff := cmp.Diff([]string{"requests-hour 3600"}, fake.calls); diff != "" {
+		t.Fatalf("invalid calls:\n%s", diff)
+	}
+}
+
+func TestRunnerRunCancel(t *testing.T) {
+	s, err := scenario.Parse([]byte(`steps: [{at: 1h, set: {requests-hour: 3600}}]`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var fake fakeClient
+
+	runner := scenario.Runner{Client: &fake}
+
+	if err := runner.Run(ctx, s); !errors.Is(err, context.Canceled) {
+		t.Fatalf("invalid error: %v", err)
+	}
+
+	if len(fake.calls) > 0 {
+		t.Fatalf("calls made: %v", fake.calls)
+	}
+}
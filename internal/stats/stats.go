@@ -0,0 +1,270 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultRetention is how long a Recorder keeps observations if it doesn't
+// specify a retention.
+const DefaultRetention = time.Hour
+
+// Recorder keeps every observation made by the generator for a limited amount
+// of time, so that exact statistics can be computed over recent windows of
+// time. Observations are grouped in buckets of one second.
+type Recorder struct {
+	Retention time.Duration
+
+	mu      sync.Mutex
+	buckets []bucket
+	first   time.Time
+}
+
+type bucket struct {
+	second    int64
+	errors    int
+	durations []float64
+}
+
+// Record records a simulated request that completed at the given time.
+func (r *Recorder) Record(t time.Time, duration float64, failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	second := t.Unix()
+
+	if r.first.IsZero() {
+		r.first = t
+	}
+
+	if n := len(r.buckets); n == 0 || r.buckets[n-1].second < second {
+		r.buckets = append(r.buckets, bucket{second: second})
+	}
+
+	// Observations are expected in chronological order. An observation
+	// older than the last bucket is recorded in the last bucket.
+	b := &r.buckets[len(r.buckets)-1]
+
+	b.durations = append(b.durations, duration)
+
+	if failed {
+		b.errors++
+	}
+
+	r.expire(second)
+}
+
+func (r *Recorder) retention() time.Duration {
+	if r.Retention <= 0 {
+		return DefaultRetention
+	}
+
+	return r.Retention
+}
+
+func (r *Recorder) expire(now int64) {
+	oldest := now - int64(r.retention()/time.Second)
+
+	i := sort.Search(len(r.buckets), func(i int) bool {
+		return r.buckets[i].second > oldest
+	})
+
+	if i > 0 {
+		r.buckets = append(r.buckets[:0], r.buckets[i:]...)
+	}
+}
+
+// Point summarizes the observations in an interval of time.
+type Point struct {
+	// Time is the end of the interval.
+	Time time.Time `json:"time"`
+	// Requests is the number of observed requests.
+	Requests int `json:"requests"`
+	// Rate is the number of observed requests per second.
+	Rate float64 `json:"rate"`
+	// Errors is the number of failed requests.
+	Errors int `json:"errors"`
+	// ErrorRatio is the ratio of failed requests, or zero if no requests
+	// were observed.
+	ErrorRatio float64 `json:"errorRatio"`
+	// P50, P90 and P99 are quantiles of the duration of the requests, or
+	// zero if no requests were observed.
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+}
+
+// Series splits the window of time ending at now in intervals of length step,
+// and summarizes the observations in each interval. The points are returned
+// in chronological order.
+func (r *Recorder) Series(now time.Time, window, step time.Duration) []Point {
+	if step < time.Second {
+		step = time.Second
+	}
+
+	stepSeconds := int64(step / time.Second)
+	count := int64(window / step)
+
+	end := now.Unix()
+	start := end - count*stepSeconds
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	points := make([]Point, count)
+
+	for i := range points {
+		pointStart := start + int64(i)*stepSeconds
+		pointEnd := pointStart + stepSeconds
+
+		errors, durations := r.collect(pointStart, pointEnd)
+
+		points[i] = summarize(time.Unix(pointEnd, 0), step, errors, durations)
+	}
+
+	return points
+}
+
+// collect returns the number of errors and the sorted durations observed in
+// the seconds in (start, end].
+func (r *Recorder) collect(start, end int64) (int, []float64) {
+	var (
+		errors    int
+		durations []float64
+	)
+
+	i := sort.Search(len(r.buckets), func(i int) bool {
+		return r.buckets[i].second > start
+	})
+
+	for ; i < len(r.buckets) && r.buckets[i].second <= end; i++ {
+		errors += r.buckets[i].errors
+		durations = append(durations, r.buckets[i].durations...)
+	}
+
+	sort.Float64s(durations)
+
+	return errors, durations
+}
+
+func summarize(t time.Time, length time.Duration, errors int, durations []float64) Point {
+	p := Point{
+		Time:     t,
+		Requests: len(durations),
+		Rate:     float64(len(durations)) / length.Seconds(),
+		Errors:   errors,
+	}
+
+	if len(durations) == 0 {
+		return p
+	}
+
+	p.ErrorRatio = float64(errors) / float64(len(durations))
+	p.P50 = Quantile(durations, 0.5)
+	p.P90 = Quantile(durations, 0.9)
+	p.P99 = Quantile(durations, 0.99)
+
+	return p
+}
+
+// Summary describes the observations in a window of time.
+type Summary struct {
+	// Window is the length of the window.
+	Window time.Duration `json:"-"`
+	// Start and End delimit the window. Start is excluded, End is included.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Complete is false if some observations in the window might be missing,
+	// because the window is longer than the retention of the Recorder or
+	// because it starts before the first observation was recorded.
+	Complete bool `json:"complete"`
+	// Requests is the number of observed requests.
+	Requests int `json:"requests"`
+	// Rate is the number of observed requests per second.
+	Rate float64 `json:"rate"`
+	// Errors is the number of failed requests.
+	Errors int `json:"errors"`
+	// ErrorRatio is the ratio of failed requests, or zero if no requests
+	// were observed.
+	ErrorRatio float64 `json:"errorRatio"`
+	// Sum, Min, Max and Mean describe the duration of the requests. Min, Max
+	// and Mean are zero if no requests were observed.
+	Sum  float64 `json:"sum"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+	// Quantiles maps every requested quantile to the corresponding exact
+	// quantile of the duration of the requests. Quantiles is empty if no
+	// requests were observed.
+	Quantiles map[float64]float64 `json:"-"`
+}
+
+// Summary summarizes the observations in the window of time ending at now,
+// computing the given quantiles of the duration.
+func (r *Recorder) Summary(now time.Time, window time.Duration, quantiles []float64) Summary {
+	end := now.Unix()
+	start := end - int64(window/time.Second)
+
+	r.mu.Lock()
+	errors, durations := r.collect(start, end)
+	complete := window <= r.retention() && !r.first.IsZero() && r.first.Unix() <= start
+	r.mu.Unlock()
+
+	s := Summary{
+		Window:    window,
+		Start:     time.Unix(start, 0),
+		End:       time.Unix(end, 0),
+		Complete:  complete,
+		Requests:  len(durations),
+		Errors:    errors,
+		Quantiles: make(map[float64]float64),
+	}
+
+	if window > 0 {
+		s.Rate = float64(len(durations)) / window.Seconds()
+	}
+
+	if len(durations) == 0 {
+		return s
+	}
+
+	for _, d := range durations {
+		s.Sum += d
+	}
+
+	s.ErrorRatio = float64(errors) / float64(len(durations))
+	s.Min = durations[0]
+	s.Max = durations[len(durations)-1]
+	s.Mean = s.Sum / float64(len(durations))
+
+	for _, q := range quantiles {
+		s.Quantiles[q] = Quantile(durations, q)
+	}
+
+	return s
+}
+
+// Quantile returns the q-quantile of sorted, interpolating linearly between
+// the closest ranks. Quantile returns NaN if sorted is empty.
+func Quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+
+	rank := q * float64(len(sorted)-1)
+
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	if lower < 0 {
+		return sorted[0]
+	}
+
+	if upper >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
@@ -0,0 +1,158 @@
+package stats_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/stats"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQuantile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{q: 0, want: 1},
+		{q: 0.5, want: 3},
+		{q: 0.9, want: 4.6},
+		{q: 1, want: 5},
+	}
+
+	for _, test := range tests {
+		if got := stats.Quantile(sorted, test.q); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("invalid %v-quantile: got %v, want %v", test.q, got, test.want)
+		}
+	}
+
+	if got := stats.Quantile(nil, 0.5); !math.IsNaN(got) {
+		t.Errorf("invalid quantile of empty input: %v", got)
+	}
+}
+
+func TestRecorderSeries(t *testing.T) {
+	var recorder stats.Recorder
+
+	now := time.Unix(1000, 0)
+
+	recorder.Record(now.Add(-25*time.Second), 4, false)
+	recorder.Record(now.Add(-15*time.Second), 1, true)
+	recorder.Record(now.Add(-15*time.Second), 3, false)
+	recorder.Record(now, 2, false)
+
+	got := recorder.Series(now, 30*time.Second, 10*time.Second)
+
+	want := []stats.Point{
+		{
+			Time:     time.Unix(980, 0),
+			Requests: 1,
+			Rate:     0.1,
+			P50:      4,
+			P90:      4,
+			P99:      4,
+		},
+		{
+			Time:       time.Unix(990, 0),
+			Requests:   2,
+			Rate:       0.2,
+			Errors:     1,
+			ErrorRatio: 0.5,
+			P50:        2,
+			P90:        2.8,
+			P99:        2.98,
+		},
+		{
+			Time:     time.Unix(1000, 0),
+			Requests: 1,
+			Rate:     0.1,
+			P50:      2,
+			P90:      2,
+			P99:      2,
+		},
+	}
+
+	if diff := cmp.Diff(want, got, cmp.Comparer(floatEqual)); diff != "" {
+		t.Fatalf("invalid series:\n%s", diff)
+	}
+}
+
+func TestRecorderRetention(t *testing.T) {
+	recorder := stats.Recorder{
+		Retention: time.Minute,
+	}
+
+	now := time.Unix(1000, 0)
+
+	recorder.Record(now.Add(-2*time.Minute), 1, false)
+	recorder.Record(now, 1, false)
+
+	points := recorder.Series(now, 5*time.Minute, 5*time.Minute)
+
+	if got := points[0].Requests; got != 1 {
+		t.Fatalf("invalid number of requests: got %d, want 1", got)
+	}
+}
+
+func floatEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRecorderSummary(t *testing.T) {
+	var recorder stats.Recorder
+
+	now := time.Unix(1000, 0)
+
+	recorder.Record(now.Add(-90*time.Second), 10, false)
+	recorder.Record(now.Add(-30*time.Second), 1, true)
+	recorder.Record(now.Add(-20*time.Second), 2, false)
+	recorder.Record(now.Add(-10*time.Second), 3, false)
+	recorder.Record(now, 6, false)
+
+	got := recorder.Summary(now, time.Minute, []float64{0.5, 0.9})
+
+	want := stats.Summary{
+		Window:     time.Minute,
+		Start:      time.Unix(940, 0),
+		End:        time.Unix(1000, 0),
+		Complete:   true,
+		Requests:   4,
+		Rate:       4.0 / 60,
+		Errors:     1,
+		ErrorRatio: 0.25,
+		Sum:        12,
+		Min:        1,
+		Max:        6,
+		Mean:       3,
+		Quantiles: map[float64]float64{
+			0.5: 2.5,
+			0.9: 5.1,
+		},
+	}
+
+	if diff := cmp.Diff(want, got, cmp.Comparer(floatEqual)); diff != "" {
+		t.Fatalf("invalid summary:\n%s", diff)
+	}
+}
+
+func TestRecorderSummaryIncomplete(t *testing.T) {
+	recorder := stats.Recorder{
+		Retention: time.Minute,
+	}
+
+	now := time.Unix(1000, 0)
+
+	recorder.Record(now.Add(-30*time.Second), 1, false)
+
+	if recorder.Summary(now, time.Minute, nil).Complete {
+		t.Fatalf("window starting before the first observation is complete")
+	}
+
+	recorder.Record(now.Add(-2*time.Minute), 1, false)
+
+	if recorder.Summary(now, 2*time.Minute, nil).Complete {
+		t.Fatalf("window longer than the retention is complete")
+	}
+}
@@ -0,0 +1,283 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Options describes how to build a TLS configuration for a server.
+type Options struct {
+	// CertFile and KeyFile are the paths to the PEM-encoded certificate and
+	// private key of the server. The files are reloaded when they change.
+	CertFile string
+	KeyFile  string
+
+	// SelfSigned generates an in-memory self-signed certificate for
+	// localhost instead of reading it from CertFile and KeyFile.
+	SelfSigned bool
+
+	// ClientCAFile is the path to the PEM-encoded certificates of the
+	// authorities used to verify client certificates.
+	ClientCAFile string
+
+	// ClientAuth is the policy for client certificates. It is one of "none",
+	// "request", "require", "verify-if-given" and "require-and-verify". If
+	// empty, it defaults to "require-and-verify" when ClientCAFile is set
+	// and to "none" otherwise.
+	ClientAuth string
+
+	// MinVersion is the minimum TLS version accepted by the server. It is
+	// one of "1.0", "1.1", "1.2" and "1.3". If empty, it defaults to "1.2".
+	MinVersion string
+
+	// Logger, if set, receives the errors reloading the certificate.
+	Logger log.Logger
+}
+
+// Enabled returns whether the options describe a TLS configuration.
+func (o Options) Enabled() bool {
+	return o.SelfSigned || o.CertFile != "" || o.KeyFile != ""
+}
+
+// Validate returns an error if client certificate verification is configured
+// without enabling TLS, which would silently serve plaintext.
+func (o Options) Validate() error {
+	if o.Enabled() {
+		return nil
+	}
+
+	if o.ClientCAFile != "" || o.ClientAuth != "" {
+		return fmt.Errorf("client certificate verification requires a certificate or a self-signed certificate")
+	}
+
+	return nil
+}
+
+// New builds a TLS configuration from the options.
+func New(o Options) (*tls.Config, error) {
+	minVersion, err := parseVersion(o.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	clientAuth, err := parseClientAuth(o.ClientAuth, o.ClientCAFile != "")
+	if err != nil {
+		return nil, err
+	}
+
+	config := tls.Config{
+		MinVersion: minVersion,
+		ClientAuth: clientAuth,
+	}
+
+	switch {
+	case o.SelfSigned && (o.CertFile != "" || o.KeyFile != ""):
+		return nil, fmt.Errorf("a self-signed certificate can't be used with a certificate or key file")
+	case o.SelfSigned:
+		cert, err := selfSignedCertificate()
+		if err != nil {
+			return nil, fmt.Errorf("generate self-signed certificate: %v", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	case o.CertFile == "" || o.KeyFile == "":
+		return nil, fmt.Errorf("both a certificate and a key file are required")
+	default:
+		reloader := certReloader{
+			certFile: o.CertFile,
+			keyFile:  o.KeyFile,
+			logger:   o.Logger,
+		}
+
+		if reloader.logger == nil {
+			reloader.logger = log.NewNopLogger()
+		}
+
+		if err := reloader.load(); err != nil {
+			return nil, err
+		}
+
+		config.GetCertificate = reloader.getCertificate
+	}
+
+	if o.ClientCAFile != "" {
+		pool, err := loadCertPool(o.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.ClientCAs = pool
+	}
+
+	return &config, nil
+}
+
+func parseVersion(value string) (uint16, error) {
+	switch value {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid TLS version %q", value)
+	}
+}
+
+func parseClientAuth(value string, hasClientCA bool) (tls.ClientAuthType, error) {
+	switch value {
+	case "":
+		if hasClientCA {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+		return tls.NoClientCert, nil
+	case "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify-if-given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require-and-verify":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("invalid client authentication policy %q", value)
+	}
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA file: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in client CA file")
+	}
+
+	return pool, nil
+}
+
+// certReloader serves a certificate read from disk, and reads it again when
+// the modification time of the certificate or the key changes. If the new
+// certificate can't be loaded, the previous one is served.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   log.Logger
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+func (r *certReloader) load() error {
+	certModTime, keyModTime, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %v", err)
+	}
+
+	r.cert = &cert
+	r.certModTime = certModTime
+	r.keyModTime = keyModTime
+
+	return nil
+}
+
+func (r *certReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("stat certificate: %v", err)
+	}
+
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("stat key: %v", err)
+	}
+
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	certModTime, keyModTime, err := r.modTimes()
+	if err != nil {
+		level.Error(r.logger).Log("msg", "Failed to reload certificate", "err", err)
+		return r.cert, nil
+	}
+
+	if certModTime.Equal(r.certModTime) && keyModTime.Equal(r.keyModTime) {
+		return r.cert, nil
+	}
+
+	if err := r.load(); err != nil {
+		level.Error(r.logger).Log("msg", "Failed to reload certificate", "err", err)
+	} else {
+		level.Info(r.logger).Log("msg", "Reloaded certificate", "cert_file", r.certFile)
+	}
+
+	return r.cert, nil
+}
+
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate key: %v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate serial number: %v", err)
+	}
+
+	now := time.Now()
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "localhost", Organization: []string{"metrics-generator"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("create certificate: %v", err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
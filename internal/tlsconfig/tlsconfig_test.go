@@ -0,0 +1,176 @@
+package tlsconfig
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewSelfSigned(t *testing.T) {
+	config, err := New(Options{SelfSigned: true, MinVersion: "1.3"})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	if len(config.Certificates) != 1 {
+		t.Fatalf("invalid number of certificates: %d", len(config.Certificates))
+	}
+
+	if config.MinVersion != tls.VersionTLS13 {
+		t.Fatalf("invalid minimum version: %x", config.MinVersion)
+	}
+
+	if config.ClientAuth != tls.NoClientCert {
+		t.Fatalf("invalid client authentication policy: %v", config.ClientAuth)
+	}
+}
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+	}{
+		{
+			name:    "missing-key",
+			options: Options{CertFile: "cert.pem"},
+		},
+		{
+			name:    "self-signed-with-cert",
+			options: Options{SelfSigned: true, CertFile: "cert.pem", KeyFile: "key.pem"},
+		},
+		{
+			name:    "invalid-version",
+			options: Options{SelfSigned: true, MinVersion: "boom"},
+		},
+		{
+			name:    "invalid-client-auth",
+			options: Options{SelfSigned: true, ClientAuth: "boom"},
+		},
+		{
+			name:    "missing-cert",
+			options: Options{CertFile: "missing.pem", KeyFile: "missing.pem"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := New(test.options); err == nil {
+				t.Fatalf("no error returned")
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []Options{
+		{},
+		{SelfSigned: true, ClientAuth: "require"},
+		{CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem"},
+	}
+
+	for _, options := range valid {
+		if err := options.Validate(); err != nil {
+			t.Errorf("invalid options %+v: %v", options, err)
+		}
+	}
+
+	invalid := []Options{
+		{ClientCAFile: "ca.pem"},
+		{ClientAuth: "require"},
+	}
+
+	for _, options := range invalid {
+		if err := options.Validate(); err == nil {
+			t.Errorf("no error returned for %+v", options)
+		}
+	}
+}
+
+func TestNewClientCA(t *testing.T) {
+	dir := t.TempDir()
+
+	certFile, _ := writeCertificate(t, dir)
+
+	config, err := New(Options{SelfSigned: true, ClientCAFile: certFile})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	if config.ClientCAs == nil {
+		t.Fatalf("client CAs not set")
+	}
+
+	if config.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("invalid client authentication policy: %v", config.ClientAuth)
+	}
+}
+
+func TestCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+
+	certFile, keyFile := writeCertificate(t, dir)
+
+	config, err := New(Options{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	first, err := config.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("get certificate: %v", err)
+	}
+
+	writeCertificate(t, dir)
+
+	// Make sure that the modification time changes even on file systems
+	// with a coarse time resolution.
+	future := time.Now().Add(time.Minute)
+
+	if err := os.Chtimes(certFile, future, future); err != nil {
+		t.Fatalf("change times: %v", err)
+	}
+
+	second, err := config.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("get certificate: %v", err)
+	}
+
+	if bytes.Equal(first.Certificate[0], second.Certificate[0]) {
+		t.Fatalf("certificate not reloaded")
+	}
+}
+
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	cert, err := selfSignedCertificate()
+	if err != nil {
+		t.Fatalf("generate certificate: %v", err)
+	}
+
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})
+
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	return certFile, keyFile
+}
@@ -0,0 +1,121 @@
+// Package tracing creates a synthetic distributed trace for every simulated
+// request, and links the traces to the exemplars of the request metrics.
+package tracing
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/exemplar"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// cacheMissRatio is the fraction of the requests that miss the fake cache and
+// query the fake database.
+const cacheMissRatio = 0.5
+
+// Tracer creates a trace for every simulated request. The root span lasts as
+// long as the request and ends when the request is observed. A failed request
+// sets the status of the root span to error.
+type Tracer struct {
+	Tracer trace.Tracer
+
+	// Rate is the fraction of the requests whose exemplar is returned, between
+	// 0 and 1. Every request is traced regardless of Rate.
+	Rate float64
+
+	// ChildSpans, if true, adds to the root span a child span for a fake
+	// cache lookup and, when the lookup misses, one for a fake database
+	// query.
+	ChildSpans bool
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// Trace creates the trace of a simulated request that ended at time now, and
+// returns the IDs of the trace and of its root span as the labels of an
+// exemplar. sampled is whether the exemplar of the request is sampled.
+func (t *Tracer) Trace(now time.Time, duration float64, failed bool) (ids map[string]string, sampled bool) {
+	start := now.Add(-time.Duration(duration * float64(time.Second)))
+
+	sampled, children := t.random(duration)
+
+	ctx, root := t.Tracer.Start(context.Background(), "request",
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.Float64("request.duration_seconds", duration)),
+	)
+
+	if t.ChildSpans {
+		t.childSpans(ctx, start, children)
+	}
+
+	if failed {
+		root.SetStatus(codes.Error, "simulated error")
+	}
+
+	root.End(trace.WithTimestamp(now))
+
+	ids = map[string]string{
+		exemplar.TraceIDLabel: root.SpanContext().TraceID().String(),
+		exemplar.SpanIDLabel:  root.SpanContext().SpanID().String(),
+	}
+
+	return ids, sampled
+}
+
+// childSpan is the interval of a fake call, relative to the start of the
+// request.
+type childSpan struct {
+	name   string
+	system string
+	start  time.Duration
+	end    time.Duration
+}
+
+func (t *Tracer) childSpans(ctx context.Context, start time.Time, children []childSpan) {
+	for _, c := range children {
+		_, span := t.Tracer.Start(ctx, c.name,
+			trace.WithTimestamp(start.Add(c.start)),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.system", c.system)),
+		)
+
+		span.End(trace.WithTimestamp(start.Add(c.end)))
+	}
+}
+
+// random decides whether the exemplar is sampled and how the duration of the
+// request is split between the fake calls.
+func (t *Tracer) random(duration float64) (bool, []childSpan) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.rand == nil {
+		t.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	sampled := t.Rate > 0 && t.rand.Float64() < t.Rate
+
+	total := time.Duration(duration * float64(time.Second))
+
+	// The cache lookup takes up to 10% of the request and the database query
+	// up to 70% of the rest, leaving time for the work of the request itself.
+	lookup := time.Duration(t.rand.Float64() * 0.1 * float64(total))
+
+	children := []childSpan{
+		{name: "cache get", system: "redis", start: 0, end: lookup},
+	}
+
+	if t.rand.Float64() < cacheMissRatio {
+		query := time.Duration(t.rand.Float64() * 0.7 * float64(total-lookup))
+		children = append(children, childSpan{name: "db query", system: "postgresql", start: lookup, end: lookup + query})
+	}
+
+	return sampled, children
+}
@@ -0,0 +1,111 @@
+package tracing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/exemplar"
+	"github.com/francescomari/metrics-generator/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTracer(rate float64, childSpans bool) (*tracing.Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	tracer := tracing.Tracer{
+		Tracer:     provider.Tracer("test"),
+		Rate:       rate,
+		ChildSpans: childSpans,
+	}
+
+	return &tracer, recorder
+}
+
+func TestTracerTrace(t *testing.T) {
+	tracer, recorder := newTracer(1, false)
+
+	now := time.Unix(1000, 0)
+
+	labels, sampled := tracer.Trace(now, 2, true)
+
+	if !sampled {
+		t.Fatalf("exemplar not sampled")
+	}
+
+	spans := recorder.Ended()
+
+	if len(spans) != 1 {
+		t.Fatalf("invalid number of spans: %d", len(spans))
+	}
+
+	root := spans[0]
+
+	if got, want := labels[exemplar.TraceIDLabel], root.SpanContext().TraceID().String(); got != want {
+		t.Fatalf("invalid trace ID: got %q, want %q", got, want)
+	}
+
+	if got, want := labels[exemplar.SpanIDLabel], root.SpanContext().SpanID().String(); got != want {
+		t.Fatalf("invalid span ID: got %q, want %q", got, want)
+	}
+
+	if !root.StartTime().Equal(now.Add(-2*time.Second)) || !root.EndTime().Equal(now) {
+		t.Fatalf("invalid span interval: %v - %v", root.StartTime(), root.EndTime())
+	}
+
+	if root.Status().Code != codes.Error {
+		t.Fatalf("invalid status: %v", root.Status())
+	}
+}
+
+func TestTracerNotSampled(t *testing.T) {
+	tracer, recorder := newTracer(0, false)
+
+	labels, sampled := tracer.Trace(time.Now(), 1, false)
+
+	if sampled {
+		t.Fatalf("exemplar sampled")
+	}
+
+	spans := recorder.Ended()
+
+	if len(spans) != 1 {
+		t.Fatalf("invalid number of spans: %d", len(spans))
+	}
+
+	if got, want := labels[exemplar.TraceIDLabel], spans[0].SpanContext().TraceID().String(); got != want {
+		t.Fatalf("invalid trace ID: got %q, want %q", got, want)
+	}
+
+	if spans[0].Status().Code == codes.Error {
+		t.Fatalf("invalid status: %v", spans[0].Status())
+	}
+}
+
+func TestTracerChildSpans(t *testing.T) {
+	tracer, recorder := newTracer(1, true)
+
+	now := time.Unix(1000, 0)
+
+	tracer.Trace(now, 10, false)
+
+	spans := recorder.Ended()
+
+	if len(spans) < 2 {
+		t.Fatalf("invalid number of spans: %d", len(spans))
+	}
+
+	root := spans[len(spans)-1]
+
+	for _, span := range spans[:len(spans)-1] {
+		if span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Fatalf("span %q is not a child of the root span", span.Name())
+		}
+
+		if span.StartTime().Before(root.StartTime()) || span.EndTime().After(root.EndTime()) {
+			t.Fatalf("span %q outside of the root span", span.Name())
+		}
+	}
+}
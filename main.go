@@ -2,62 +2,201 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
-	"log"
+	stdlog "log"
 	"math/rand"
+	"net"
 	"net/http"
+	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/francescomari/httprun"
+	"github.com/francescomari/metrics-generator/internal/accesslog"
+	"github.com/francescomari/metrics-generator/internal/anomaly"
 	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/auth"
+	"github.com/francescomari/metrics-generator/internal/cardinality"
+	"github.com/francescomari/metrics-generator/internal/configfile"
+	"github.com/francescomari/metrics-generator/internal/events"
+	"github.com/francescomari/metrics-generator/internal/exemplar"
+	"github.com/francescomari/metrics-generator/internal/faults"
+	"github.com/francescomari/metrics-generator/internal/gaps"
+	"github.com/francescomari/metrics-generator/internal/gauges"
+	"github.com/francescomari/metrics-generator/internal/grpcapi"
+	"github.com/francescomari/metrics-generator/internal/health"
 	"github.com/francescomari/metrics-generator/internal/limits"
 	"github.com/francescomari/metrics-generator/internal/metrics"
+	"github.com/francescomari/metrics-generator/internal/restart"
+	"github.com/francescomari/metrics-generator/internal/stats"
+	"github.com/francescomari/metrics-generator/internal/tlsconfig"
+	"github.com/francescomari/metrics-generator/internal/tracing"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/promlog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	grpccredentials "google.golang.org/grpc/credentials"
 )
 
-var requestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
-	Name: "metrics_generator_request_duration_seconds",
-	Help: "Request duration in seconds",
+// selfRegistry contains the metrics about the generator itself, as opposed to
+// the synthetic metrics in the default registry. Depending on -self-metrics,
+// they are served together with the synthetic metrics or on their own
+// endpoint.
+var selfRegistry = prometheus.NewRegistry()
+
+var configLastReloadSuccessful = promauto.With(selfRegistry).NewGauge(prometheus.GaugeOpts{
+	Name: "metrics_generator_config_last_reload_successful",
+	Help: "Whether the last configuration reload attempt was successful",
+})
+
+var configLastReloadSuccessTimestamp = promauto.With(selfRegistry).NewGauge(prometheus.GaugeOpts{
+	Name: "metrics_generator_config_last_reload_success_timestamp_seconds",
+	Help: "Timestamp of the last successful configuration reload",
+})
+
+var configReloadsTotal = promauto.With(selfRegistry).NewCounter(prometheus.CounterOpts{
+	Name: "metrics_generator_config_reloads_total",
+	Help: "Number of successful configuration reloads",
+})
+
+var configReloadFailuresTotal = promauto.With(selfRegistry).NewCounter(prometheus.CounterOpts{
+	Name: "metrics_generator_config_reload_failures_total",
+	Help: "Number of failed configuration reloads",
+})
+
+var unauthorizedRequestsTotal = promauto.With(selfRegistry).NewCounter(prometheus.CounterOpts{
+	Name: "metrics_generator_unauthorized_requests_total",
+	Help: "Number of API requests rejected because of missing or insufficient credentials",
 })
 
-var requestErrorsCount = promauto.NewCounter(prometheus.CounterOpts{
-	Name: "metrics_generator_request_errors_count",
-	Help: "Number of errors observed in requests",
+var configChangesTotal = promauto.With(selfRegistry).NewCounter(prometheus.CounterOpts{
+	Name: "metrics_generator_config_changes_total",
+	Help: "Number of configuration changes, through the API or a reload",
 })
 
+var apiRequestsTotal = promauto.With(selfRegistry).NewCounterVec(prometheus.CounterOpts{
+	Name: "metrics_generator_api_requests_total",
+	Help: "Number of requests served by the API, by route, method and status code",
+}, []string{"route", "method", "code"})
+
+var apiRequestDuration = promauto.With(selfRegistry).NewHistogramVec(prometheus.HistogramOpts{
+	Name: "metrics_generator_api_request_duration_seconds",
+	Help: "Duration of the requests served by the API, by route and method",
+}, []string{"route", "method"})
+
 func main() {
 	if err := run(); err != nil {
-		log.Fatalf("error: %v", err)
+		if _, ok := err.(reportedError); !ok {
+			stdlog.Printf("error: %v", err)
+		}
+
+		os.Exit(1)
 	}
 }
 
+// reportedError is an error that was already reported to the user, and only
+// needs to change the exit status.
+type reportedError struct {
+	error
+}
+
 func run() error {
 	rand.Seed(time.Now().Unix())
 
+	args := os.Args[1:]
+
+	if isCLICommand(args) {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		if err := runCLICommand(ctx, args, os.Stdout, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return reportedError{err}
+		}
+
+		return nil
+	}
+
+	if len(args) > 0 && args[0] == "serve" {
+		args = args[1:]
+	}
+
 	var g metricsGenerator
 
-	flag.StringVar(&g.address, "addr", ":8080", "The address to listen to")
-	flag.IntVar(&g.minDuration, "duration-min", 1, "Minimum request duration")
-	flag.IntVar(&g.maxDuration, "duration-max", 10, "Maximum request duration")
-	flag.IntVar(&g.reqHour, "requests-hour", 1000, "Metric generation rate")
-	flag.Float64Var(&g.errorsPercentage, "errors-percentage", 10, "Which percentage of the requests will fail")
-	flag.Parse()
+	if err := g.parseFlags(args, flag.ExitOnError); err != nil {
+		return err
+	}
+
+	g.logger = promlog.New(&promlog.Config{
+		Level:  &g.logLevel,
+		Format: &g.logFormat,
+	})
+
+	g.tls.Logger = g.logger
+
+	if err := g.run(); err != nil {
+		level.Error(g.logger).Log("msg", "Failed to run", "err", err)
+		return reportedError{err}
+	}
 
-	return g.run()
+	return nil
 }
 
 type metricsGenerator struct {
-	address          string
-	minDuration      int
-	maxDuration      int
-	reqHour          int
-	errorsPercentage float64
+	args                 []string
+	configFile           string
+	configCheckInterval  time.Duration
+	address              string
+	adminAddress         string
+	grpcAddress          string
+	eventsBufferSize     int
+	statsRetention       time.Duration
+	statsWindows         durationList
+	selfMetrics          string
+	readyMaxLag          time.Duration
+	liveMaxLag           time.Duration
+	tls                  tlsconfig.Options
+	authFile             string
+	minDuration          int
+	maxDuration          int
+	reqHour              int
+	errorsPercentage     float64
+	logLevel             promlog.AllowedLevel
+	logFormat            promlog.AllowedFormat
+	logRequests          bool
+	restartLabel         string
+	cardinalitySeries    int
+	cardinalityChurnRate float64
+	cardinalityLabel     string
+	summary              bool
+	summaryObjectives    objectiveList
+	summaryMaxAge        time.Duration
+	summaryAgeBuckets    int
+	exemplarSamplingRate float64
+	otlpEndpoint         string
+	otlpInsecure         bool
+	otlpServiceName      string
+	traceChildSpans      bool
+	accessLog            string
+	accessLogFormat      string
+	accessLogMaxSize     int64
+	accessLogMaxBackups  int
+	logger               log.Logger
 }
 
 func (g *metricsGenerator) run() error {
@@ -66,13 +205,37 @@ func (g *metricsGenerator) run() error {
 		return err
 	}
 
+	configLastReloadSuccessful.Set(1)
+	configLastReloadSuccessTimestamp.SetToCurrentTime()
+
+	level.Info(g.logger).Log(
+		"msg", "Starting metrics generator",
+		"addr", g.address,
+		"admin_addr", g.adminAddress,
+		"grpc_addr", g.grpcAddress,
+		"config_file", g.configFile,
+		"tls", g.tls.Enabled(),
+		"auth", g.authFile != "",
+		"duration_min", g.minDuration,
+		"duration_max", g.maxDuration,
+		"requests_hour", g.reqHour,
+		"errors_percentage", g.errorsPercentage,
+	)
+
 	ctx, cancel := g.setupSignalHandler()
 	defer cancel()
 
+	go func() {
+		<-ctx.Done()
+		level.Info(g.logger).Log("msg", "Shutting down")
+	}()
+
 	if err := g.runServices(ctx, config); err != nil {
 		return fmt.Errorf("run services: %v", err)
 	}
 
+	level.Info(g.logger).Log("msg", "Stopped")
+
 	return nil
 }
 
@@ -99,42 +262,573 @@ func (g *metricsGenerator) setupSignalHandler() (context.Context, context.Cancel
 }
 
 func (g *metricsGenerator) runServices(ctx context.Context, config *limits.Config) error {
+	reloader := configReloader{
+		args:   g.args,
+		config: config,
+		logger: g.logger,
+	}
+
+	broker := events.Broker{
+		BufferSize: g.eventsBufferSize,
+	}
+
+	config.Subscribe(func() {
+		broker.Publish(configEvent(config))
+	})
+
+	recorder := stats.Recorder{
+		Retention: g.statsRetention,
+	}
+
+	var injector faults.Injector
+
+	var anomalies anomaly.Set
+
+	var gapSet gaps.Gaps
+
+	simulator := cardinality.Simulator{
+		Label: g.cardinalityLabel,
+	}
+
+	if err := simulator.Set(g.cardinalitySeries, g.cardinalityChurnRate, time.Now()); err != nil {
+		return fmt.Errorf("cardinality: %v", err)
+	}
+
+	process := restart.Process{
+		Label: g.restartLabel,
+		Start: time.Now(),
+	}
+
+	restarter := processRestarter{
+		process: &process,
+		logger:  g.logger,
+	}
+
+	checker := health.Checker{
+		ReadyMaxLag: g.readyMaxLag,
+		LiveMaxLag:  g.liveMaxLag,
+	}
+
+	config.Subscribe(configChangesTotal.Inc)
+
+	config.Subscribe(func() {
+		min, max := config.DurationInterval()
+
+		level.Info(g.logger).Log(
+			"msg", "Configuration changed",
+			"duration_min", min,
+			"duration_max", max,
+			"requests_hour", config.RequestsHour(),
+			"errors_percentage", config.ErrorsPercentage(),
+		)
+	})
+
+	gaugeSet := gauges.Set{
+		Traffic: &recorder,
+		Offset: func(name string, now time.Time) float64 {
+			if name == memoryUsageGauge {
+				return anomalies.Effects(now).Drift
+			}
+			return 0
+		},
+	}
+
+	for _, gauge := range defaultGauges {
+		if err := gaugeSet.Put(gauge); err != nil {
+			return fmt.Errorf("gauge %s: %v", gauge.Name, err)
+		}
+	}
+
+	exemplars := exemplar.Sampler{
+		Rate: g.exemplarSamplingRate,
+	}
+
+	var tracer metrics.Tracer
+
+	if g.otlpEndpoint != "" {
+		otlpTracer, shutdown, err := g.newTracer(ctx)
+		if err != nil {
+			return fmt.Errorf("tracing: %v", err)
+		}
+
+		defer shutdown()
+
+		tracer = otlpTracer
+	}
+
+	var accessLog metrics.AccessLog
+
+	if g.accessLog != "" {
+		writer, err := accesslog.Open(g.accessLog, g.accessLogMaxSize<<20, g.accessLogMaxBackups)
+		if err != nil {
+			return fmt.Errorf("access log: %v", err)
+		}
+
+		defer writer.Close()
+
+		accessLog = &accesslog.Logger{
+			Format: g.accessLogFormat,
+			Writer: writer,
+			Logger: g.logger,
+		}
+	}
+
+	requests := registerSyntheticMetrics(&process, g.summaryOpts(), &simulator, &gaugeSet)
+
+	registerRuntimeMetrics(&process)
+
+	registerSelfMetrics(config, &recorder, &checker, &process, &anomalies, &simulator)
+
+	credentials, err := g.loadCredentials()
+	if err != nil {
+		return err
+	}
+
+	// Every server shares the same TLS configuration, so that they present
+	// the same certificate, even when it is self-signed.
+	var tlsConfig *tls.Config
+
+	if g.tls.Enabled() {
+		tlsConfig, err = tlsconfig.New(g.tls)
+		if err != nil {
+			return fmt.Errorf("TLS configuration: %v", err)
+		}
+	}
+
+	servers := g.buildServers(config, &reloader, &broker, &recorder, &checker, &injector, &anomalies, &simulator, &gapSet, &gaugeSet, restarter, credentials)
+
 	group, ctx := errgroup.WithContext(ctx)
 
 	group.Go(func() error {
-		return g.runMetricsGenerator(ctx, config)
+		return g.runMetricsGenerator(ctx, config, requests, &exemplars, tracer, accessLog, &anomalies, &gapSet, &broker, &recorder, &checker)
 	})
 
+	for _, s := range servers {
+		s := s
+
+		group.Go(func() error {
+			return g.runHTTPServer(ctx, s, tlsConfig)
+		})
+	}
+
+	if g.grpcAddress != "" {
+		group.Go(func() error {
+			return g.runGRPCServer(ctx, config, &broker, credentials, tlsConfig)
+		})
+	}
+
 	group.Go(func() error {
-		return g.runAPIServer(ctx, config)
+		return g.runReloadSignalHandler(ctx, &reloader)
 	})
 
+	if g.configFile != "" && g.configCheckInterval > 0 {
+		group.Go(func() error {
+			return g.runConfigFileWatcher(ctx, &reloader)
+		})
+	}
+
 	return group.Wait()
 }
 
-func (g *metricsGenerator) runMetricsGenerator(ctx context.Context, config *limits.Config) error {
+func (g *metricsGenerator) runMetricsGenerator(ctx context.Context, config *limits.Config, requests requestMetrics, exemplars metrics.Exemplars, tracer metrics.Tracer, accessLog metrics.AccessLog, anomalies *anomaly.Set, pause *gaps.Gaps, broker *events.Broker, recorder *stats.Recorder, checker *health.Checker) error {
 	generator := metrics.Generator{
-		Config:   config,
-		Duration: requestDuration,
-		Errors:   requestErrorsCount,
+		Config:      config,
+		Duration:    requests.duration,
+		Summary:     requests.summary,
+		Errors:      requests.errors,
+		ErrorsTotal: requests.errorsTotal,
+		Exemplars:   exemplars,
+		Tracer:      tracer,
+		AccessLog:   accessLog,
+		Anomalies:   anomalies,
+		Pause:       pause,
+		Events:      broker,
+		Stats:       recorder,
+		Heartbeat:   checker,
+		Logger:      g.logger,
+		LogRequests: g.logRequests,
 	}
 
-	if err := g.handleMetricsGeneratorError(generator.Run(ctx)); err != nil {
+	if err := g.handleRunError(generator.Run(ctx)); err != nil {
 		return fmt.Errorf("metrics generator: %v", err)
 	}
 
 	return nil
 }
 
-func (g *metricsGenerator) runAPIServer(ctx context.Context, config *limits.Config) error {
+type httpServer struct {
+	name    string
+	address string
+	handler http.Handler
+}
+
+// memoryUsageGauge is the gauge that grows while a drift anomaly is active.
+const memoryUsageGauge = "memory_usage_bytes"
+
+// defaultGauges are the synthetic gauges exposed at startup.
+var defaultGauges = []gauges.Gauge{
+	{
+		Name:  "in_flight_requests",
+		Help:  "Simulated requests in flight, derived from the rate and the duration of the requests",
+		Model: gauges.Model{Type: gauges.TypeTraffic},
+	},
+	{
+		Name:  "queue_depth",
+		Help:  "Simulated number of queued jobs",
+		Model: gauges.Model{Type: gauges.TypeRandomWalk, Min: 0, Max: 100, Volatility: 1},
+	},
+	{
+		Name:  "cpu_utilization_ratio",
+		Help:  "Simulated CPU utilization",
+		Model: gauges.Model{Type: gauges.TypeMeanReverting, Min: 0, Max: 1, Mean: 0.3, Reversion: 0.05, Volatility: 0.02},
+	},
+	{
+		Name:  memoryUsageGauge,
+		Help:  "Simulated memory usage, growing while a drift anomaly is active",
+		Model: gauges.Model{Type: gauges.TypeSawtooth, Min: 64 << 20, Max: 96 << 20, Period: 10 * time.Minute},
+	},
+	{
+		Name:  "temperature_celsius",
+		Help:  "Simulated temperature",
+		Model: gauges.Model{Type: gauges.TypeSine, Min: 18, Max: 26, Period: time.Hour},
+	},
+}
+
+// requestMetrics are the synthetic metrics observing the simulated requests.
+type requestMetrics struct {
+	duration    metrics.Histogram
+	summary     metrics.Histogram
+	errors      metrics.Counter
+	errorsTotal metrics.Counter
+}
+
+// summaryOpts returns the options of the request duration summary, or nil if
+// the summary is disabled.
+func (g *metricsGenerator) summaryOpts() *prometheus.SummaryOpts {
+	if !g.summary {
+		return nil
+	}
+
+	return &prometheus.SummaryOpts{
+		Name:       "metrics_generator_request_duration_summary_seconds",
+		Help:       "Request duration in seconds, as a summary",
+		Objectives: g.summaryObjectives.Objectives(),
+		MaxAge:     g.summaryMaxAge,
+		AgeBuckets: uint32(g.summaryAgeBuckets),
+	}
+}
+
+// registerSyntheticMetrics registers the synthetic metrics in the default
+// registry. The request metrics belong to the simulated process, and are
+// reset when it restarts. The request duration is also exposed as a summary if
+// summaryOpts is not nil.
+func registerSyntheticMetrics(process *restart.Process, summaryOpts *prometheus.SummaryOpts, simulator *cardinality.Simulator, gaugeSet *gauges.Set) requestMetrics {
+	duration := process.NewHistogram(prometheus.HistogramOpts{
+		Name: "metrics_generator_request_duration_seconds",
+		Help: "Request duration in seconds",
+	})
+
+	errors := process.NewCounter(prometheus.CounterOpts{
+		Name: "metrics_generator_request_errors_count",
+		Help: "Number of errors observed in requests",
+	})
+
+	// The OpenMetrics format requires the name of a counter to end in
+	// _total, and exposes the counter above with type unknown. This one
+	// counts the same errors, and carries their exemplars.
+	errorsTotal := process.NewCounter(prometheus.CounterOpts{
+		Name: "metrics_generator_request_errors_total",
+		Help: "Number of errors observed in requests, with exemplars",
+	})
+
+	prometheus.MustRegister(duration, errors, errorsTotal, simulator, gaugeSet)
+
+	requests := requestMetrics{
+		duration:    duration,
+		errors:      errors,
+		errorsTotal: errorsTotal,
+	}
+
+	if summaryOpts != nil {
+		summary := process.NewSummary(*summaryOpts)
+		prometheus.MustRegister(summary)
+		requests.summary = summary
+	}
+
+	return requests
+}
+
+// registerRuntimeMetrics moves the collectors of the Go runtime and of the
+// process from the default registry to the registry of the self-monitoring
+// metrics, so that they are not served with the synthetic metrics when the
+// self-monitoring metrics are separate. The process collector is replaced by
+// one reporting the start time of the simulated process.
+func registerRuntimeMetrics(process *restart.Process) {
+	goCollector := prometheus.NewGoCollector()
+	processCollector := prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{})
+
+	prometheus.Unregister(goCollector)
+	prometheus.Unregister(processCollector)
+
+	selfRegistry.MustRegister(goCollector, process.WrapProcessCollector(processCollector))
+}
+
+// registerSelfMetrics registers the metrics that are computed from the state
+// of the generator when they are collected.
+func registerSelfMetrics(config *limits.Config, recorder *stats.Recorder, checker *health.Checker, process *restart.Process, anomalies *anomaly.Set, simulator *cardinality.Simulator) {
+	selfRegistry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "metrics_generator_config_errors_percentage",
+			Help: "Configured percentage of simulated requests that fail",
+		}, config.ErrorsPercentage),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "metrics_generator_config_duration_min_seconds",
+			Help: "Configured minimum duration of the simulated requests",
+		}, func() float64 {
+			min, _ := config.DurationInterval()
+			return float64(min)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "metrics_generator_config_duration_max_seconds",
+			Help: "Configured maximum duration of the simulated requests",
+		}, func() float64 {
+			_, max := config.DurationInterval()
+			return float64(max)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "metrics_generator_config_requests_per_hour",
+			Help: "Configured number of simulated requests per hour",
+		}, func() float64 {
+			return float64(config.RequestsHour())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "metrics_generator_loop_lag_seconds",
+			Help: "How late the generation loop is compared to its schedule",
+		}, func() float64 {
+			result, _ := checker.Check(health.ProbeLive, time.Now())
+			return result.Lag
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "metrics_generator_observation_rate",
+			Help: "Number of simulated requests per second in the last minute",
+		}, func() float64 {
+			return recorder.Summary(time.Now(), time.Minute, nil).Rate
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "metrics_generator_simulated_restarts_total",
+			Help: "Number of simulated restarts of the process exposing the synthetic metrics",
+		}, func() float64 {
+			return float64(process.Restarts())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "metrics_generator_active_anomalies",
+			Help: "Number of anomalies in effect",
+		}, func() float64 {
+			var active int
+
+			for _, a := range anomalies.List(time.Now()) {
+				if a.Active {
+					active++
+				}
+			}
+
+			return float64(active)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "metrics_generator_cardinality_active_series",
+			Help: "Number of series currently exposed to simulate a cardinality explosion",
+		}, func() float64 {
+			return float64(simulator.State(time.Now()).ActiveSeries)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "metrics_generator_cardinality_churned_series_total",
+			Help: "Number of series retired to simulate a cardinality explosion",
+		}, func() float64 {
+			return float64(simulator.State(time.Now()).ChurnedSeries)
+		}),
+	)
+}
+
+// apiObserver records the requests served by the API in the self-monitoring
+// metrics.
+type apiObserver struct{}
+
+func (apiObserver) ObserveRequest(route, method string, code int, duration time.Duration) {
+	apiRequestsTotal.WithLabelValues(route, method, strconv.Itoa(code)).Inc()
+	apiRequestDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// processRestarter simulates a restart of the process exposing the synthetic
+// metrics, and logs it.
+type processRestarter struct {
+	process *restart.Process
+	logger  log.Logger
+}
+
+func (r processRestarter) Restart(now time.Time) {
+	r.process.Restart(now)
+	level.Info(r.logger).Log("msg", "Simulated restart", "start_time", now)
+}
+
+// newTracer returns a tracer exporting the synthetic traces to the OTLP
+// endpoint, and a function flushing the pending traces and closing the
+// exporter. The exemplars returned by the tracer point to its traces.
+func (g *metricsGenerator) newTracer(ctx context.Context) (*tracing.Tracer, func(), error) {
+	options := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(g.otlpEndpoint),
+	}
+
+	if g.otlpInsecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, options...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create exporter: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(g.otlpServiceName),
+		)),
+	)
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		level.Warn(g.logger).Log("msg", "Failed to export traces", "err", err)
+	}))
+
+	tracer := tracing.Tracer{
+		Tracer:     provider.Tracer("github.com/francescomari/metrics-generator"),
+		Rate:       g.exemplarSamplingRate,
+		ChildSpans: g.traceChildSpans,
+	}
+
+	shutdown := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := provider.Shutdown(ctx); err != nil {
+			level.Warn(g.logger).Log("msg", "Failed to flush traces", "err", err)
+		}
+	}
+
+	level.Info(g.logger).Log("msg", "Exporting traces", "endpoint", g.otlpEndpoint)
+
+	return &tracer, shutdown, nil
+}
+
+// metricsHandlers returns the handler for the metrics endpoint and, if the
+// self-monitoring metrics are served separately, the handler for the
+// self-monitoring metrics. The series hidden by gapSet are removed from the
+// metrics endpoint.
+func (g *metricsGenerator) metricsHandlers(gapSet *gaps.Gaps) (http.Handler, http.Handler) {
+	var gatherer prometheus.Gatherer = prometheus.DefaultGatherer
+
+	var selfMetricsHandler http.Handler
+
+	if g.selfMetrics == selfMetricsSeparate {
+		selfMetricsHandler = promhttp.HandlerFor(selfRegistry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+	} else {
+		gatherer = prometheus.Gatherers{
+			prometheus.DefaultGatherer,
+			selfRegistry,
+		}
+	}
+
+	metricsHandler := promhttp.HandlerFor(gapSet.Gatherer(gatherer), promhttp.HandlerOpts{EnableOpenMetrics: true})
+
+	return promhttp.InstrumentMetricHandler(selfRegistry, metricsHandler), selfMetricsHandler
+}
+
+// loadCredentials returns the credentials required to change the
+// configuration, or nil if authentication is disabled.
+func (g *metricsGenerator) loadCredentials() (*auth.Credentials, error) {
+	if g.authFile == "" {
+		return nil, nil
+	}
+
+	credentials, err := auth.Load(g.authFile)
+	if err != nil {
+		return nil, fmt.Errorf("load credentials: %v", err)
+	}
+
+	return credentials, nil
+}
+
+// buildServers returns the HTTP servers to run. By default, a single server
+// serves every endpoint. If an admin address is configured, the metrics are
+// served on the main address and every other endpoint on the admin address.
+func (g *metricsGenerator) buildServers(config *limits.Config, reloader *configReloader, broker *events.Broker, recorder *stats.Recorder, checker *health.Checker, injector *faults.Injector, anomalies *anomaly.Set, simulator *cardinality.Simulator, gapSet *gaps.Gaps, gaugeSet *gauges.Set, restarter processRestarter, credentials *auth.Credentials) []httpServer {
+	metricsHandler, selfMetricsHandler := g.metricsHandlers(gapSet)
+
 	handler := api.Handler{
-		Config:  config,
-		Metrics: promhttp.Handler(),
+		Config:       config,
+		Metrics:      metricsHandler,
+		SelfMetrics:  selfMetricsHandler,
+		Requests:     apiObserver{},
+		Logger:       g.logger,
+		Reloader:     reloader,
+		Events:       broker,
+		Stats:        recorder,
+		StatsWindows: g.statsWindows,
+		Probes:       checker,
+		Faults:       injector,
+		Anomalies:    anomalies,
+		Cardinality:  simulator,
+		Gaps:         gapSet,
+		Gauges:       gaugeSet,
+		Restarter:    restarter,
+		Unauthorized: unauthorizedRequestsTotal,
+	}
+
+	if credentials != nil {
+		handler.Authenticator = credentials
+	}
+
+	if g.adminAddress == "" {
+		return []httpServer{{name: "API server", address: g.address, handler: &handler}}
+	}
+
+	metricsOnlyHandler := api.Handler{
+		Metrics:     handler.Metrics,
+		SelfMetrics: handler.SelfMetrics,
+		Probes:      checker,
+		Faults:      injector,
+		Requests:    handler.Requests,
+		Logger:      g.logger,
+		MetricsOnly: true,
+	}
+
+	handler.Metrics = nil
+	handler.SelfMetrics = nil
+
+	servers := []httpServer{
+		{name: "metrics server", address: g.address, handler: &metricsOnlyHandler},
+		{name: "admin server", address: g.adminAddress, handler: &handler},
 	}
 
+	return servers
+}
+
+// runHTTPServer serves s, over TLS if tlsConfig is not nil.
+func (g *metricsGenerator) runHTTPServer(ctx context.Context, s httpServer, tlsConfig *tls.Config) error {
+	listener, err := listen(s.address)
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.name, err)
+	}
+
+	level.Info(g.logger).Log("msg", "Listening", "server", s.name, "addr", s.address, "tls", tlsConfig != nil)
+
+	// Requests are bound to the context, so that long-running requests like
+	// event streams are terminated when the server shuts down.
 	server := http.Server{
-		Addr:    g.address,
-		Handler: &handler,
+		Handler:   s.handler,
+		TLSConfig: tlsConfig,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
 	}
 
 	runServer := httprun.Server{
@@ -142,14 +836,139 @@ func (g *metricsGenerator) runAPIServer(ctx context.Context, config *limits.Conf
 		ShutdownTimeout: time.Second,
 	}
 
-	if err := runServer.ListenAndServe(ctx); err != nil {
-		return fmt.Errorf("API server: %v", err)
+	if tlsConfig != nil {
+		err = runServer.ServeTLS(ctx, listener, "", "")
+	} else {
+		err = runServer.Serve(ctx, listener)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.name, err)
+	}
+
+	return nil
+}
+
+// runGRPCServer serves the gRPC control-plane service. It uses the same TLS
+// configuration and credentials as the HTTP servers.
+func (g *metricsGenerator) runGRPCServer(ctx context.Context, config *limits.Config, broker *events.Broker, credentials *auth.Credentials, tlsConfig *tls.Config) error {
+	service := grpcapi.Server{
+		Config:       config,
+		Events:       broker,
+		Unauthorized: unauthorizedRequestsTotal,
+		Logger:       g.logger,
+	}
+
+	if credentials != nil {
+		service.Authenticator = credentials
+	}
+
+	var opts []grpc.ServerOption
+
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(grpccredentials.NewTLS(tlsConfig)))
+	}
+
+	listener, err := listen(g.grpcAddress)
+	if err != nil {
+		return fmt.Errorf("gRPC server: %v", err)
+	}
+
+	level.Info(g.logger).Log("msg", "Listening", "server", "gRPC server", "addr", g.grpcAddress, "tls", tlsConfig != nil)
+
+	server := service.NewGRPCServer(opts...)
+
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("gRPC server: %v", err)
+	case <-ctx.Done():
 	}
 
+	// Watch streams only end when the server stops, so a graceful stop is
+	// given the same time as the HTTP servers before closing them.
+	timer := time.AfterFunc(time.Second, server.Stop)
+	defer timer.Stop()
+
+	server.GracefulStop()
+
 	return nil
 }
 
-func (g *metricsGenerator) handleMetricsGeneratorError(err error) error {
+// listen listens on a TCP address or, if the address starts with "unix:", on
+// a Unix domain socket. A stale socket file left behind by a previous process
+// is removed before listening.
+func listen(address string) (net.Listener, error) {
+	path := strings.TrimPrefix(address, "unix:")
+
+	if path == address {
+		return net.Listen("tcp", address)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %v", err)
+		}
+	}
+
+	return net.Listen("unix", path)
+}
+
+func configEvent(config *limits.Config) events.Event {
+	min, max := config.DurationInterval()
+
+	return events.Event{
+		Type: events.TypeConfig,
+		Time: time.Now(),
+		Config: &events.Config{
+			MinDuration:      min,
+			MaxDuration:      max,
+			ErrorsPercentage: config.ErrorsPercentage(),
+			RequestsHour:     config.RequestsHour(),
+		},
+	}
+}
+
+func (g *metricsGenerator) runReloadSignalHandler(ctx context.Context, reloader *configReloader) error {
+	signals := make(chan os.Signal, 1)
+
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-signals:
+			level.Info(g.logger).Log("msg", "Reloading configuration", "reason", "SIGHUP")
+			reloader.Reload()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (g *metricsGenerator) runConfigFileWatcher(ctx context.Context, reloader *configReloader) error {
+	watcher := configfile.Watcher{
+		Path:     g.configFile,
+		Interval: g.configCheckInterval,
+		OnChange: func() {
+			level.Info(g.logger).Log("msg", "Reloading configuration", "reason", "file changed")
+			reloader.Reload()
+		},
+	}
+
+	if err := g.handleRunError(watcher.Run(ctx)); err != nil {
+		return fmt.Errorf("config file watcher: %v", err)
+	}
+
+	return nil
+}
+
+func (g *metricsGenerator) handleRunError(err error) error {
 	switch err {
 	case context.Canceled:
 		return nil
@@ -157,3 +976,51 @@ func (g *metricsGenerator) handleMetricsGeneratorError(err error) error {
 		return err
 	}
 }
+
+// configReloader re-reads the settings from the command line arguments and
+// the configuration file, and applies them to the running generator. Settings
+// that are only used at startup, like the listen address, are not affected by
+// a reload.
+type configReloader struct {
+	mu     sync.Mutex
+	args   []string
+	config *limits.Config
+	logger log.Logger
+}
+
+func (r *configReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.reload(); err != nil {
+		configLastReloadSuccessful.Set(0)
+		configReloadFailuresTotal.Inc()
+		level.Error(r.logger).Log("msg", "Failed to reload configuration", "err", err)
+		return err
+	}
+
+	configLastReloadSuccessful.Set(1)
+	configLastReloadSuccessTimestamp.SetToCurrentTime()
+	configReloadsTotal.Inc()
+
+	level.Info(r.logger).Log("msg", "Reloaded configuration")
+
+	return nil
+}
+
+func (r *configReloader) reload() error {
+	var g metricsGenerator
+
+	if err := g.parseFlags(r.args, flag.ContinueOnError); err != nil {
+		return err
+	}
+
+	config, err := g.buildLimitsConfig()
+	if err != nil {
+		return err
+	}
+
+	r.config.CopyFrom(config)
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/cardinality"
+	"github.com/francescomari/metrics-generator/internal/exemplar"
+	"github.com/francescomari/metrics-generator/internal/gaps"
+	"github.com/francescomari/metrics-generator/internal/gauges"
+	"github.com/francescomari/metrics-generator/internal/limits"
+	"github.com/francescomari/metrics-generator/internal/metrics"
+	"github.com/francescomari/metrics-generator/internal/restart"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type fixedExemplar map[string]string
+
+func (e fixedExemplar) Exemplar() map[string]string {
+	return e
+}
+
+// useDefaultRegistry replaces the default registry with an empty one for the
+// duration of the test.
+func useDefaultRegistry(t *testing.T) {
+	t.Helper()
+
+	registerer, gatherer := prometheus.DefaultRegisterer, prometheus.DefaultGatherer
+
+	t.Cleanup(func() {
+		prometheus.DefaultRegisterer, prometheus.DefaultGatherer = registerer, gatherer
+	})
+
+	registry := prometheus.NewRegistry()
+
+	prometheus.DefaultRegisterer, prometheus.DefaultGatherer = registry, registry
+}
+
+func TestMetricsHandlerOpenMetrics(t *testing.T) {
+	useDefaultRegistry(t)
+
+	var config limits.Config
+
+	if err := config.SetDurationInterval(2, 2); err != nil {
+		t.Fatalf("set duration interval: %v", err)
+	}
+
+	if err := config.SetErrorsPercentage(100); err != nil {
+		t.Fatalf("set errors percentage: %v", err)
+	}
+
+	if err := config.SetRequestsHour(3600); err != nil {
+		t.Fatalf("set requests hour: %v", err)
+	}
+
+	process := restart.Process{Start: time.Now()}
+
+	requests := registerSyntheticMetrics(&process, nil, &cardinality.Simulator{}, &gauges.Set{})
+
+	generator := metrics.Generator{
+		Config:      &config,
+		Duration:    requests.duration,
+		Errors:      requests.errors,
+		ErrorsTotal: requests.errorsTotal,
+		Exemplars:   fixedExemplar{exemplar.TraceIDLabel: "0af7651916cd43dd8448eb211c80319c"},
+	}
+
+	// The generator simulates a request before it checks the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := generator.Run(ctx); err != context.Canceled {
+		t.Fatalf("run: %v", err)
+	}
+
+	var g metricsGenerator
+
+	handler, _ := g.metricsHandlers(&gaps.Gaps{})
+
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	request.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+
+	response := httptest.NewRecorder()
+
+	handler.ServeHTTP(response, request)
+
+	if contentType := response.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/openmetrics-text") {
+		t.Fatalf("invalid content type: %q", contentType)
+	}
+
+	lines := strings.Split(response.Body.String(), "\n")
+
+	for _, want := range []string{
+		"# TYPE metrics_generator_request_errors counter",
+		`metrics_generator_request_errors_total 1.0 # {trace_id="0af7651916cd43dd8448eb211c80319c"} 1.0`,
+		"# TYPE metrics_generator_request_errors_count unknown",
+		"metrics_generator_request_errors_count 1.0",
+		`metrics_generator_request_duration_seconds_bucket{le="2.5"} 1 # {trace_id="0af7651916cd43dd8448eb211c80319c"} 2.0`,
+	} {
+		if !containsLine(lines, want) {
+			t.Errorf("line not found: %s", want)
+		}
+	}
+
+	if t.Failed() {
+		t.Logf("response:\n%s", response.Body.String())
+	}
+}
+
+// containsLine returns whether one of the lines is equal to want. If want has
+// an exemplar, the timestamp of the exemplar is ignored.
+func containsLine(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want || strings.Contains(want, " # {") && strings.HasPrefix(line, want+" ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestMetricsHandlerSeparateSelfMetrics(t *testing.T) {
+	useDefaultRegistry(t)
+
+	// Mimic the collectors registered by default.
+	prometheus.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	process := restart.Process{Start: time.Now()}
+
+	registerSyntheticMetrics(&process, nil, &cardinality.Simulator{}, &gauges.Set{})
+	registerRuntimeMetrics(&process)
+
+	g := metricsGenerator{selfMetrics: selfMetricsSeparate}
+
+	handler, selfHandler := g.metricsHandlers(&gaps.Gaps{})
+
+	// The instrumentation of the handler only reports the first request
+	// after it completes.
+	scrape(handler)
+
+	for _, line := range strings.Split(scrape(handler), "\n") {
+		if strings.HasPrefix(line, "promhttp_") || strings.HasPrefix(line, "go_") {
+			t.Errorf("self-monitoring series served with the synthetic metrics: %s", line)
+		}
+	}
+
+	self := scrape(selfHandler)
+
+	for _, want := range []string{"promhttp_metric_handler_requests_total", "go_goroutines"} {
+		if !strings.Contains(self, want) {
+			t.Errorf("self-monitoring metric not found: %s", want)
+		}
+	}
+}
+
+func scrape(handler http.Handler) string {
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return response.Body.String()
+}
@@ -0,0 +1,389 @@
+// Package client is a client for the API of a running Metrics Generator.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidValue is matched by errors returned when the server rejects
+	// a value because it is malformed or out of range.
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrUnauthorized is matched by errors returned when the credentials are
+	// missing or invalid.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is matched by errors returned when the credentials don't
+	// allow the operation.
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound is matched by errors returned when the server doesn't
+	// provide the endpoint, e.g. because the feature is disabled.
+	ErrNotFound = errors.New("not found")
+)
+
+// Error is returned when the server responds with an unexpected status code.
+// Use errors.Is with ErrInvalidValue, ErrUnauthorized, ErrForbidden and
+// ErrNotFound to check for common failures.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server responded with status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("server responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrInvalidValue:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	default:
+		return false
+	}
+}
+
+// Client calls the API of a running Metrics Generator.
+type Client struct {
+	// BaseURL is the URL of the generator, e.g. "http://localhost:8080".
+	BaseURL string
+
+	// HTTPClient is used to send requests. If nil, http.DefaultClient is
+	// used.
+	HTTPClient *http.Client
+
+	// Token, if set, is sent as a bearer token.
+	Token string
+
+	// Username and Password, if Username is set, are sent with basic
+	// authentication.
+	Username string
+	Password string
+}
+
+// Config is the whole configuration of the generator.
+type Config struct {
+	DurationInterval DurationInterval `json:"durationInterval"`
+	ErrorsPercentage float64          `json:"errorsPercentage"`
+	RequestsHour     int              `json:"requestsHour"`
+}
+
+// DurationInterval is the interval of the simulated durations, in seconds.
+type DurationInterval struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Stats contains statistics about the generated requests.
+type Stats struct {
+	Windows []WindowStats `json:"windows"`
+}
+
+// WindowStats contains statistics about the requests generated in a window
+// of time.
+type WindowStats struct {
+	Window     string             `json:"window"`
+	Start      time.Time          `json:"start"`
+	End        time.Time          `json:"end"`
+	Complete   bool               `json:"complete"`
+	Requests   int                `json:"requests"`
+	Rate       float64            `json:"rate"`
+	Errors     int                `json:"errors"`
+	ErrorRatio float64            `json:"errorRatio"`
+	Sum        float64            `json:"sum"`
+	Min        float64            `json:"min"`
+	Max        float64            `json:"max"`
+	Mean       float64            `json:"mean"`
+	Quantiles  map[string]float64 `json:"quantiles"`
+}
+
+// HiddenSeries is a rule hiding series from the metrics endpoint.
+type HiddenSeries struct {
+	ID     string            `json:"id"`
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Until  *time.Time        `json:"until,omitempty"`
+}
+
+// Health returns nil if the generator is healthy.
+func (c *Client) Health(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodGet, "/-/health", "")
+	return err
+}
+
+// Config returns the whole configuration.
+func (c *Client) Config(ctx context.Context) (*Config, error) {
+	var config Config
+
+	if err := c.getJSON(ctx, "/-/config", &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// DurationInterval returns the interval of the simulated durations.
+func (c *Client) DurationInterval(ctx context.Context) (DurationInterval, error) {
+	body, err := c.do(ctx, http.MethodGet, "/-/config/duration-interval", "")
+	if err != nil {
+		return DurationInterval{}, err
+	}
+
+	parts := strings.Split(body, ",")
+
+	if len(parts) != 2 {
+		return DurationInterval{}, fmt.Errorf("invalid duration interval %q", body)
+	}
+
+	min, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return DurationInterval{}, fmt.Errorf("invalid minimum duration: %v", err)
+	}
+
+	max, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return DurationInterval{}, fmt.Errorf("invalid maximum duration: %v", err)
+	}
+
+	return DurationInterval{Min: min, Max: max}, nil
+}
+
+// SetDurationInterval changes the interval of the simulated durations.
+func (c *Client) SetDurationInterval(ctx context.Context, min, max int) error {
+	_, err := c.do(ctx, http.MethodPut, "/-/config/duration-interval", fmt.Sprintf("%d,%d", min, max))
+	return err
+}
+
+// ErrorsPercentage returns the percentage of simulated requests that fail.
+func (c *Client) ErrorsPercentage(ctx context.Context) (float64, error) {
+	body, err := c.do(ctx, http.MethodGet, "/-/config/errors-percentage", "")
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.ParseFloat(body, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid errors percentage: %v", err)
+	}
+
+	return value, nil
+}
+
+// SetErrorsPercentage changes the percentage of simulated requests that fail.
+func (c *Client) SetErrorsPercentage(ctx context.Context, value float64) error {
+	_, err := c.do(ctx, http.MethodPut, "/-/config/errors-percentage", strconv.FormatFloat(value, 'f', -1, 64))
+	return err
+}
+
+// RequestsHour returns the number of simulated requests per hour.
+func (c *Client) RequestsHour(ctx context.Context) (int, error) {
+	body, err := c.do(ctx, http.MethodGet, "/-/config/requests-hour", "")
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.Atoi(body)
+	if err != nil {
+		return 0, fmt.Errorf("invalid requests per hour: %v", err)
+	}
+
+	return value, nil
+}
+
+// SetRequestsHour changes the number of simulated requests per hour.
+func (c *Client) SetRequestsHour(ctx context.Context, value int) error {
+	_, err := c.do(ctx, http.MethodPut, "/-/config/requests-hour", strconv.Itoa(value))
+	return err
+}
+
+// Reload reloads the configuration file of the generator.
+func (c *Client) Reload(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodPost, "/-/reload", "")
+	return err
+}
+
+// Restart simulates a restart of the generator, resetting the synthetic
+// metrics.
+func (c *Client) Restart(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodPost, "/-/restart", "")
+	return err
+}
+
+// HideSeries hides from the metrics endpoint the series of a metric matching
+// the labels, or every series of the metric if labels is empty. If duration
+// is positive, the series return after that long, otherwise they are hidden
+// until ShowSeries is called.
+func (c *Client) HideSeries(ctx context.Context, metric string, labels map[string]string, duration time.Duration) (*HiddenSeries, error) {
+	request := struct {
+		Metric   string            `json:"metric"`
+		Labels   map[string]string `json:"labels,omitempty"`
+		Duration string            `json:"duration,omitempty"`
+	}{
+		Metric: metric,
+		Labels: labels,
+	}
+
+	if duration > 0 {
+		request.Duration = duration.String()
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %v", err)
+	}
+
+	body, err := c.send(ctx, http.MethodPost, "/-/gaps/series", "application/json", string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var series HiddenSeries
+
+	if err := json.Unmarshal([]byte(body), &series); err != nil {
+		return nil, fmt.Errorf("decode response: %v", err)
+	}
+
+	return &series, nil
+}
+
+// ShowSeries shows again the series hidden by the rule with the given ID.
+func (c *Client) ShowSeries(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/-/gaps/series/"+url.PathEscape(id), "")
+	return err
+}
+
+// Pause pauses the observation of simulated requests. If duration is
+// positive, observation resumes after that long, otherwise it is paused until
+// Resume is called.
+func (c *Client) Pause(ctx context.Context, duration time.Duration) error {
+	path := "/-/gaps/pause"
+
+	if duration > 0 {
+		path += "?" + url.Values{"duration": {duration.String()}}.Encode()
+	}
+
+	_, err := c.do(ctx, http.MethodPut, path, "")
+	return err
+}
+
+// Resume resumes the observation of simulated requests.
+func (c *Client) Resume(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodDelete, "/-/gaps/pause", "")
+	return err
+}
+
+// Stats returns statistics about the generated requests. If windows or
+// quantiles are empty, the defaults of the server are used.
+func (c *Client) Stats(ctx context.Context, windows []time.Duration, quantiles []float64) (*Stats, error) {
+	query := url.Values{}
+
+	for _, w := range windows {
+		query.Add("window", w.String())
+	}
+
+	for _, q := range quantiles {
+		query.Add("quantile", strconv.FormatFloat(q, 'f', -1, 64))
+	}
+
+	path := "/-/stats"
+
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var stats Stats
+
+	if err := c.getJSON(ctx, path, &stats); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, v interface{}) error {
+	body, err := c.do(ctx, http.MethodGet, path, "")
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		return fmt.Errorf("decode response: %v", err)
+	}
+
+	return nil
+}
+
+// do sends a request with a plain text body. See send.
+func (c *Client) do(ctx context.Context, method, path, body string) (string, error) {
+	return c.send(ctx, method, path, "text/plain", body)
+}
+
+// send sends a request and returns the body of a successful response, with
+// leading and trailing white space removed.
+func (c *Client) send(ctx context.Context, method, path, contentType, body string) (string, error) {
+	var reader io.Reader
+
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, reader)
+	if err != nil {
+		return "", fmt.Errorf("create request: %v", err)
+	}
+
+	if body != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	if c.Username != "" {
+		request.SetBasicAuth(c.Username, c.Password)
+	}
+
+	httpClient := c.HTTPClient
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("read response: %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
+		return "", &Error{
+			StatusCode: response.StatusCode,
+			Message:    strings.TrimSpace(string(data)),
+		}
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
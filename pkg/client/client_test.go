@@ -0,0 +1,224 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/francescomari/metrics-generator/internal/api"
+	"github.com/francescomari/metrics-generator/internal/auth"
+	"github.com/francescomari/metrics-generator/internal/gaps"
+	"github.com/francescomari/metrics-generator/internal/limits"
+	"github.com/francescomari/metrics-generator/internal/stats"
+	"github.com/francescomari/metrics-generator/pkg/client"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newServer(t *testing.T, handler *api.Handler) *client.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return &client.Client{
+		BaseURL: server.URL,
+	}
+}
+
+func newConfig(t *testing.T) *limits.Config {
+	t.Helper()
+
+	var config limits.Config
+
+	if err := config.SetDurationInterval(1, 10); err != nil {
+		t.Fatalf("set duration interval: %v", err)
+	}
+
+	if err := config.SetErrorsPercentage(10); err != nil {
+		t.Fatalf("set errors percentage: %v", err)
+	}
+
+	if err := config.SetRequestsHour(1000); err != nil {
+		t.Fatalf("set requests hour: %v", err)
+	}
+
+	return &config
+}
+
+func TestClientConfig(t *testing.T) {
+	c := newServer(t, &api.Handler{Config: newConfig(t)})
+
+	ctx := context.Background()
+
+	if err := c.Health(ctx); err != nil {
+		t.Fatalf("health: %v", err)
+	}
+
+	if err := c.SetDurationInterval(ctx, 15, 45); err != nil {
+		t.Fatalf("set duration interval: %v", err)
+	}
+
+	if err := c.SetErrorsPercentage(ctx, 25.5); err != nil {
+		t.Fatalf("set errors percentage: %v", err)
+	}
+
+	if err := c.SetRequestsHour(ctx, 2000); err != nil {
+		t.Fatalf("set requests hour: %v", err)
+	}
+
+	interval, err := c.DurationInterval(ctx)
+	if err != nil {
+		t.Fatalf("duration interval: %v", err)
+	}
+
+	if diff := cmp.Diff(client.DurationInterval{Min: 15, Max: 45}, interval); diff != "" {
+		t.Fatalf("invalid duration interval:\n%s", diff)
+	}
+
+	percentage, err := c.ErrorsPercentage(ctx)
+	if err != nil {
+		t.Fatalf("errors percentage: %v", err)
+	}
+
+	if percentage != 25.5 {
+		t.Fatalf("invalid errors percentage: %v", percentage)
+	}
+
+	requestsHour, err := c.RequestsHour(ctx)
+	if err != nil {
+		t.Fatalf("requests hour: %v", err)
+	}
+
+	if requestsHour != 2000 {
+		t.Fatalf("invalid requests hour: %v", requestsHour)
+	}
+
+	config, err := c.Config(ctx)
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+
+	want := client.Config{
+		DurationInterval: client.DurationInterval{Min: 15, Max: 45},
+		ErrorsPercentage: 25.5,
+		RequestsHour:     2000,
+	}
+
+	if diff := cmp.Diff(&want, config); diff != "" {
+		t.Fatalf("invalid config:\n%s", diff)
+	}
+}
+
+func TestClientInvalidValue(t *testing.T) {
+	c := newServer(t, &api.Handler{Config: newConfig(t)})
+
+	err := c.SetErrorsPercentage(context.Background(), 200)
+
+	if !errors.Is(err, client.ErrInvalidValue) {
+		t.Fatalf("invalid error: %v", err)
+	}
+
+	var clientErr *client.Error
+
+	if !errors.As(err, &clientErr) || clientErr.Message == "" {
+		t.Fatalf("error message not returned: %v", err)
+	}
+}
+
+func TestClientAuthentication(t *testing.T) {
+	credentials, err := auth.Parse([]byte("tokens:\n  - token: secret\n    role: admin\n"))
+	if err != nil {
+		t.Fatalf("parse credentials: %v", err)
+	}
+
+	c := newServer(t, &api.Handler{
+		Config:        newConfig(t),
+		Authenticator: credentials,
+	})
+
+	ctx := context.Background()
+
+	if err := c.SetRequestsHour(ctx, 10); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("invalid error: %v", err)
+	}
+
+	c.Token = "secret"
+
+	if err := c.SetRequestsHour(ctx, 10); err != nil {
+		t.Fatalf("set requests hour: %v", err)
+	}
+}
+
+func TestClientReloadNotFound(t *testing.T) {
+	c := newServer(t, &api.Handler{Config: newConfig(t)})
+
+	if err := c.Reload(context.Background()); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("invalid error: %v", err)
+	}
+}
+
+func TestClientStats(t *testing.T) {
+	var recorder stats.Recorder
+
+	recorder.Record(time.Now(), 2, true)
+
+	c := newServer(t, &api.Handler{Stats: &recorder})
+
+	s, err := c.Stats(context.Background(), []time.Duration{time.Minute}, []float64{0.5})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+
+	if len(s.Windows) != 1 {
+		t.Fatalf("invalid number of windows: %d", len(s.Windows))
+	}
+
+	w := s.Windows[0]
+
+	if w.Window != "1m" || w.Requests != 1 || w.Errors != 1 || w.Quantiles["0.5"] != 2 {
+		t.Fatalf("invalid stats: %+v", w)
+	}
+}
+
+func TestClientGaps(t *testing.T) {
+	var g gaps.Gaps
+
+	c := newServer(t, &api.Handler{Gaps: &g})
+
+	ctx := context.Background()
+
+	series, err := c.HideSeries(ctx, "metrics_generator_request_duration_seconds", map[string]string{"le": "+Inf"}, time.Minute)
+	if err != nil {
+		t.Fatalf("hide series: %v", err)
+	}
+
+	if series.ID == "" || series.Metric != "metrics_generator_request_duration_seconds" || series.Until == nil {
+		t.Fatalf("invalid hidden series: %+v", series)
+	}
+
+	if err := c.Pause(ctx, time.Minute); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+
+	if state := g.State(time.Now()); !state.Paused || state.PausedUntil.IsZero() || len(state.Hidden) != 1 {
+		t.Fatalf("invalid state: %+v", state)
+	}
+
+	if err := c.ShowSeries(ctx, series.ID); err != nil {
+		t.Fatalf("show series: %v", err)
+	}
+
+	if err := c.Resume(ctx); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+
+	if state := g.State(time.Now()); state.Paused || len(state.Hidden) != 0 {
+		t.Fatalf("invalid state: %+v", state)
+	}
+
+	if err := c.ShowSeries(ctx, series.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("invalid error: %v", err)
+	}
+}
@@ -0,0 +1,413 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: control.proto
+
+package controlpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ControlClient is the client API for Control service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ControlClient interface {
+	// GetConfig returns the whole configuration.
+	GetConfig(ctx context.Context, in *GetConfigRequest, opts ...grpc.CallOption) (*Config, error)
+	// GetDurationInterval returns the interval of the simulated durations.
+	GetDurationInterval(ctx context.Context, in *GetDurationIntervalRequest, opts ...grpc.CallOption) (*DurationInterval, error)
+	// SetDurationInterval changes the interval of the simulated durations.
+	// Both bounds must be greater than zero, and the minimum must not be
+	// greater than the maximum.
+	SetDurationInterval(ctx context.Context, in *SetDurationIntervalRequest, opts ...grpc.CallOption) (*DurationInterval, error)
+	// GetErrorsPercentage returns the percentage of simulated requests that
+	// fail.
+	GetErrorsPercentage(ctx context.Context, in *GetErrorsPercentageRequest, opts ...grpc.CallOption) (*ErrorsPercentage, error)
+	// SetErrorsPercentage changes the percentage of simulated requests that
+	// fail. The value must be between 0 and 100.
+	SetErrorsPercentage(ctx context.Context, in *SetErrorsPercentageRequest, opts ...grpc.CallOption) (*ErrorsPercentage, error)
+	// GetRequestsHour returns the number of simulated requests per hour.
+	GetRequestsHour(ctx context.Context, in *GetRequestsHourRequest, opts ...grpc.CallOption) (*RequestsHour, error)
+	// SetRequestsHour changes the number of simulated requests per hour. The
+	// value must be greater than zero.
+	SetRequestsHour(ctx context.Context, in *SetRequestsHourRequest, opts ...grpc.CallOption) (*RequestsHour, error)
+	// WatchConfig sends the current configuration, and then the new
+	// configuration every time it changes.
+	WatchConfig(ctx context.Context, in *WatchConfigRequest, opts ...grpc.CallOption) (Control_WatchConfigClient, error)
+}
+
+type controlClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewControlClient(cc grpc.ClientConnInterface) ControlClient {
+	return &controlClient{cc}
+}
+
+func (c *controlClient) GetConfig(ctx context.Context, in *GetConfigRequest, opts ...grpc.CallOption) (*Config, error) {
+	out := new(Config)
+	err := c.cc.Invoke(ctx, "/metricsgenerator.v1.Control/GetConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) GetDurationInterval(ctx context.Context, in *GetDurationIntervalRequest, opts ...grpc.CallOption) (*DurationInterval, error) {
+	out := new(DurationInterval)
+	err := c.cc.Invoke(ctx, "/metricsgenerator.v1.Control/GetDurationInterval", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) SetDurationInterval(ctx context.Context, in *SetDurationIntervalRequest, opts ...grpc.CallOption) (*DurationInterval, error) {
+	out := new(DurationInterval)
+	err := c.cc.Invoke(ctx, "/metricsgenerator.v1.Control/SetDurationInterval", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) GetErrorsPercentage(ctx context.Context, in *GetErrorsPercentageRequest, opts ...grpc.CallOption) (*ErrorsPercentage, error) {
+	out := new(ErrorsPercentage)
+	err := c.cc.Invoke(ctx, "/metricsgenerator.v1.Control/GetErrorsPercentage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) SetErrorsPercentage(ctx context.Context, in *SetErrorsPercentageRequest, opts ...grpc.CallOption) (*ErrorsPercentage, error) {
+	out := new(ErrorsPercentage)
+	err := c.cc.Invoke(ctx, "/metricsgenerator.v1.Control/SetErrorsPercentage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) GetRequestsHour(ctx context.Context, in *GetRequestsHourRequest, opts ...grpc.CallOption) (*RequestsHour, error) {
+	out := new(RequestsHour)
+	err := c.cc.Invoke(ctx, "/metricsgenerator.v1.Control/GetRequestsHour", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) SetRequestsHour(ctx context.Context, in *SetRequestsHourRequest, opts ...grpc.CallOption) (*RequestsHour, error) {
+	out := new(RequestsHour)
+	err := c.cc.Invoke(ctx, "/metricsgenerator.v1.Control/SetRequestsHour", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) WatchConfig(ctx context.Context, in *WatchConfigRequest, opts ...grpc.CallOption) (Control_WatchConfigClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Control_ServiceDesc.Streams[0], "/metricsgenerator.v1.Control/WatchConfig", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &controlWatchConfigClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Control_WatchConfigClient interface {
+	Recv() (*Config, error)
+	grpc.ClientStream
+}
+
+type controlWatchConfigClient struct {
+	grpc.ClientStream
+}
+
+func (x *controlWatchConfigClient) Recv() (*Config, error) {
+	m := new(Config)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ControlServer is the server API for Control service.
+// All implementations must embed UnimplementedControlServer
+// for forward compatibility
+type ControlServer interface {
+	// GetConfig returns the whole configuration.
+	GetConfig(context.Context, *GetConfigRequest) (*Config, error)
+	// GetDurationInterval returns the interval of the simulated durations.
+	GetDurationInterval(context.Context, *GetDurationIntervalRequest) (*DurationInterval, error)
+	// SetDurationInterval changes the interval of the simulated durations.
+	// Both bounds must be greater than zero, and the minimum must not be
+	// greater than the maximum.
+	SetDurationInterval(context.Context, *SetDurationIntervalRequest) (*DurationInterval, error)
+	// GetErrorsPercentage returns the percentage of simulated requests that
+	// fail.
+	GetErrorsPercentage(context.Context, *GetErrorsPercentageRequest) (*ErrorsPercentage, error)
+	// SetErrorsPercentage changes the percentage of simulated requests that
+	// fail. The value must be between 0 and 100.
+	SetErrorsPercentage(context.Context, *SetErrorsPercentageRequest) (*ErrorsPercentage, error)
+	// GetRequestsHour returns the number of simulated requests per hour.
+	GetRequestsHour(context.Context, *GetRequestsHourRequest) (*RequestsHour, error)
+	// SetRequestsHour changes the number of simulated requests per hour. The
+	// value must be greater than zero.
+	SetRequestsHour(context.Context, *SetRequestsHourRequest) (*RequestsHour, error)
+	// WatchConfig sends the current configuration, and then the new
+	// configuration every time it changes.
+	WatchConfig(*WatchConfigRequest, Control_WatchConfigServer) error
+	mustEmbedUnimplementedControlServer()
+}
+
+// UnimplementedControlServer must be embedded to have forward compatible implementations.
+type UnimplementedControlServer struct {
+}
+
+func (UnimplementedControlServer) GetConfig(context.Context, *GetConfigRequest) (*Config, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConfig not implemented")
+}
+func (UnimplementedControlServer) GetDurationInterval(context.Context, *GetDurationIntervalRequest) (*DurationInterval, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDurationInterval not implemented")
+}
+func (UnimplementedControlServer) SetDurationInterval(context.Context, *SetDurationIntervalRequest) (*DurationInterval, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDurationInterval not implemented")
+}
+func (UnimplementedControlServer) GetErrorsPercentage(context.Context, *GetErrorsPercentageRequest) (*ErrorsPercentage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetErrorsPercentage not implemented")
+}
+func (UnimplementedControlServer) SetErrorsPercentage(context.Context, *SetErrorsPercentageRequest) (*ErrorsPercentage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetErrorsPercentage not implemented")
+}
+func (UnimplementedControlServer) GetRequestsHour(context.Context, *GetRequestsHourRequest) (*RequestsHour, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRequestsHour not implemented")
+}
+func (UnimplementedControlServer) SetRequestsHour(context.Context, *SetRequestsHourRequest) (*RequestsHour, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetRequestsHour not implemented")
+}
+func (UnimplementedControlServer) WatchConfig(*WatchConfigRequest, Control_WatchConfigServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchConfig not implemented")
+}
+func (UnimplementedControlServer) mustEmbedUnimplementedControlServer() {}
+
+// UnsafeControlServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ControlServer will
+// result in compilation errors.
+type UnsafeControlServer interface {
+	mustEmbedUnimplementedControlServer()
+}
+
+func RegisterControlServer(s grpc.ServiceRegistrar, srv ControlServer) {
+	s.RegisterService(&Control_ServiceDesc, srv)
+}
+
+func _Control_GetConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).GetConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metricsgenerator.v1.Control/GetConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).GetConfig(ctx, req.(*GetConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_GetDurationInterval_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDurationIntervalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).GetDurationInterval(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metricsgenerator.v1.Control/GetDurationInterval",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).GetDurationInterval(ctx, req.(*GetDurationIntervalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_SetDurationInterval_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDurationIntervalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).SetDurationInterval(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metricsgenerator.v1.Control/SetDurationInterval",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).SetDurationInterval(ctx, req.(*SetDurationIntervalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_GetErrorsPercentage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetErrorsPercentageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).GetErrorsPercentage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metricsgenerator.v1.Control/GetErrorsPercentage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).GetErrorsPercentage(ctx, req.(*GetErrorsPercentageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_SetErrorsPercentage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetErrorsPercentageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).SetErrorsPercentage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metricsgenerator.v1.Control/SetErrorsPercentage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).SetErrorsPercentage(ctx, req.(*SetErrorsPercentageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_GetRequestsHour_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequestsHourRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).GetRequestsHour(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metricsgenerator.v1.Control/GetRequestsHour",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).GetRequestsHour(ctx, req.(*GetRequestsHourRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_SetRequestsHour_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequestsHourRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).SetRequestsHour(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metricsgenerator.v1.Control/SetRequestsHour",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).SetRequestsHour(ctx, req.(*SetRequestsHourRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_WatchConfig_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchConfigRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ControlServer).WatchConfig(m, &controlWatchConfigServer{stream})
+}
+
+type Control_WatchConfigServer interface {
+	Send(*Config) error
+	grpc.ServerStream
+}
+
+type controlWatchConfigServer struct {
+	grpc.ServerStream
+}
+
+func (x *controlWatchConfigServer) Send(m *Config) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Control_ServiceDesc is the grpc.ServiceDesc for Control service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Control_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "metricsgenerator.v1.Control",
+	HandlerType: (*ControlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetConfig",
+			Handler:    _Control_GetConfig_Handler,
+		},
+		{
+			MethodName: "GetDurationInterval",
+			Handler:    _Control_GetDurationInterval_Handler,
+		},
+		{
+			MethodName: "SetDurationInterval",
+			Handler:    _Control_SetDurationInterval_Handler,
+		},
+		{
+			MethodName: "GetErrorsPercentage",
+			Handler:    _Control_GetErrorsPercentage_Handler,
+		},
+		{
+			MethodName: "SetErrorsPercentage",
+			Handler:    _Control_SetErrorsPercentage_Handler,
+		},
+		{
+			MethodName: "GetRequestsHour",
+			Handler:    _Control_GetRequestsHour_Handler,
+		},
+		{
+			MethodName: "SetRequestsHour",
+			Handler:    _Control_SetRequestsHour_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchConfig",
+			Handler:       _Control_WatchConfig_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "control.proto",
+}
@@ -0,0 +1,5 @@
+// Package controlpb contains the gRPC service to control a running Metrics
+// Generator, generated from control.proto.
+package controlpb
+
+//go:generate buf generate